@@ -7,9 +7,15 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"github.com/stubborn-gaga-0805/aurora/conf"
+	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/stubborn-gaga-0805/aurora/pkg/buildcache"
+	"github.com/stubborn-gaga-0805/aurora/pkg/dotenv"
+	"github.com/stubborn-gaga-0805/aurora/pkg/manifest"
+	"github.com/stubborn-gaga-0805/aurora/pkg/tools"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
 type baseCmd struct {
@@ -24,6 +30,19 @@ type baseCmd struct {
 	mainPath       string
 	binPath        string
 	hasBin         bool
+	forceBuild     bool
+	profileName    string
+	profile        manifest.Profile
+	environ        *dotenv.Env
+	manifest       *manifest.Manifest
+	manifestErr    error
+
+	// customConfig 通过 -c 指定了配置文件, 不再分层加载
+	customConfig bool
+	// sets --set 覆盖的配置项
+	sets []string
+	// config 分层加载的配置
+	config *conf.Layered
 }
 
 func newBaseCmd() *baseCmd {
@@ -49,6 +68,8 @@ func newBaseCmd() *baseCmd {
 	if os.IsNotExist(err) {
 		bc.hasBin = false
 	}
+	// 项目配置文件(aurora.yaml), 解析失败时在使用时报错
+	bc.manifest, bc.manifestErr = manifest.Load(bc.workingDir)
 	return bc
 }
 
@@ -64,34 +85,182 @@ func (base *baseCmd) addCommands(commands ...cmder) {
 
 func (base *baseCmd) initConfig() {
 	var (
-		configs *conf.App
-		err     error
+		configs  *conf.App
+		settings map[string]interface{}
+		err      error
 	)
 
-	// 设置配置文件
-	viper.SetConfigType("yaml")
-	viper.SetConfigFile(base.configFilePath)
+	// 分层加载配置文件
+	if base.config, err = base.loadConfig(); err != nil {
+		fmt.Printf("🚫 Failed to read the config file...[%v]\n💡 Check it with: aurora config validate -e %s\n", err, base.env)
+		os.Exit(1)
+		return
+	}
+	if settings, err = base.config.Settings(); err != nil {
+		fmt.Printf("🚫 Failed to read the config file...[%v]\n💡 Check it with: aurora config validate -e %s\n", err, base.env)
+		os.Exit(1)
+		return
+	}
 	// 读取配置文件到结构体
-	if err = viper.ReadInConfig(); err != nil {
-		panic(err)
+	viper.SetConfigType("yaml")
+	if err = viper.MergeConfigMap(settings); err != nil {
+		fmt.Printf("🚫 Failed to read the config file...[%v]\n💡 Check it with: aurora config validate -e %s\n", err, base.env)
+		os.Exit(1)
+		return
 	}
 	if err = viper.Unmarshal(&configs); err != nil {
-		panic(err)
+		fmt.Printf("🚫 Failed to parse the config file...[%v]\n💡 Check it with: aurora config validate -e %s\n", err, base.env)
+		os.Exit(1)
+		return
 	}
 	conf.SetConfig(configs)
 
 	return
 }
 
+// loadConfig 分层加载配置: configs/config.yaml、configs/config.<env>.yaml、环境变量 (AURORA_*) 以及 --set
+func (base *baseCmd) loadConfig() (*conf.Layered, error) {
+	opts := conf.LoadOptions{
+		Dir:     filepath.Join(base.workingDir, "configs"),
+		Env:     base.env.ToString(),
+		EnvFile: base.env.ConfigFile(),
+		Environ: os.Environ(),
+		Sets:    base.sets,
+	}
+	if base.environ != nil {
+		opts.Environ = base.environ.Environ()
+	}
+	if base.customConfig {
+		opts.File = base.configFilePath
+	}
+	return conf.LoadLayered(opts)
+}
+
+// serverConfigFile 传递给服务进程的配置文件, 只有一个配置文件时直接使用, 配置有多个来源时将合并后的配置写入 ./bin/config.<env>.yaml
+func (base *baseCmd) serverConfigFile() string {
+	if !base.config.Overridden() {
+		return base.config.Files[0]
+	}
+	content, err := base.config.YAML()
+	if err != nil {
+		fmt.Printf("🚫 Failed to merge the config files...[%v]\n", err)
+		os.Exit(1)
+		return ""
+	}
+	path := filepath.Join(base.workingDir, "bin", base.env.ConfigFile())
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		err = os.WriteFile(path, append([]byte("# Generated by aurora from "+strings.Join(base.relFiles(base.config.Files), ", ")+", DO NOT EDIT.\n"), content...), 0600)
+	}
+	if err != nil {
+		fmt.Printf("🚫 Failed to write the merged config file...[%v]\n", err)
+		os.Exit(1)
+		return ""
+	}
+	return path
+}
+
+// relFiles 相对于项目目录的路径
+func (base *baseCmd) relFiles(files []string) []string {
+	rel := make([]string, len(files))
+	for i, file := range files {
+		if r, err := filepath.Rel(base.workingDir, file); err == nil {
+			file = r
+		}
+		rel[i] = file
+	}
+	return rel
+}
+
+// 加载env文件并与进程环境变量合并, 未指定 --env-file 时默认加载 .env 和 .env.<env>
+func (base *baseCmd) initEnvironment(cmd *cobra.Command) {
+	files := getEnvFiles(cmd)
+	if len(files) == 0 {
+		files = base.defaultEnvFiles()
+	}
+	environ, err := dotenv.Load(files...)
+	if err != nil {
+		fmt.Printf("🚫 Failed to load env file...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	// 子进程优先使用项目 ./bin/tools 中的工具
+	if path := tools.PrependPath(base.workingDir, environ.Get("PATH")); path != environ.Get("PATH") {
+		environ.Set("PATH", path, dotenv.SourceAurora)
+	}
+	base.environ = environ
+	return
+}
+
+// resolveEnv 加载env文件并确定运行环境: 进程环境变量或env文件中的 RUNTIME_ENV, 未设置时为默认环境
+func (base *baseCmd) resolveEnv(cmd *cobra.Command) {
+	base.env = Env(os.Getenv(consts.OSEnvKey))
+	base.initEnvironment(cmd)
+	// .env 中设置了 RUNTIME_ENV 时, 重新加载以包含 .env.<env>
+	if env := Env(base.environ.Get(consts.OSEnvKey)); env != base.env {
+		base.env = env
+		base.initEnvironment(cmd)
+	}
+	if len(base.env) == 0 {
+		base.env = defaultRuntimeEnv()
+	}
+	checkRuntimeEnv(base.env)
+	base.environ.Set(consts.OSEnvKey, base.env.ToString(), dotenv.SourceAurora)
+	return
+}
+
+func (base *baseCmd) defaultEnvFiles() []string {
+	var (
+		files      = make([]string, 0, 2)
+		candidates = []string{".env"}
+	)
+	if len(base.env) > 0 {
+		candidates = append(candidates, fmt.Sprintf(".env.%s", base.env))
+	}
+	for _, name := range candidates {
+		path := filepath.Join(base.workingDir, name)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// Environ 子进程使用的环境变量
+func (base *baseCmd) Environ() []string {
+	if base.environ == nil {
+		return append(os.Environ(), "PATH="+tools.PrependPath(base.workingDir, os.Getenv("PATH")))
+	}
+	return base.environ.Environ()
+}
+
+// Manifest 项目配置(aurora.yaml), 文件不存在时返回空配置
+func (base *baseCmd) Manifest() *manifest.Manifest {
+	if base.manifestErr != nil {
+		fmt.Printf("🚫 Failed to load the project manifest...[%v]\n", base.manifestErr)
+		os.Exit(1)
+		return nil
+	}
+	return base.manifest
+}
+
+// GetBin 获取二进制文件, 源码或编译参数发生变化时重新编译
 func (base *baseCmd) GetBin() string {
-	if base.hasBin {
-		return base.binPath
+	if base.hasBin && !base.forceBuild {
+		sum, err := base.buildSum()
+		if err == nil && buildcache.Fresh(base.binPath, sum) {
+			fmt.Printf("⚡️ Sources unchanged, reuse [%s] (use --%s to rebuild)...\n", base.binPath, flagForceBuild.name)
+			return base.binPath
+		}
 	}
 	return base.Build()
 }
 
 func (base *baseCmd) Build() string {
-	fd := exec.Command("go", "build", "-o", base.binPath, base.mainPath)
+	if len(base.profileName) > 0 {
+		fmt.Printf("🧰 Build profile: [%s]\n", base.profileName)
+	}
+	fd := exec.Command("go", base.buildArgs()...)
+	fd.Env = append(os.Environ(), base.profile.Environ()...)
 	fd.Stdout = os.Stdout
 	fd.Stderr = os.Stderr
 	if err := fd.Run(); err != nil {
@@ -100,9 +269,80 @@ func (base *baseCmd) Build() string {
 		return ""
 	}
 	base.hasBin = true
+	// 记录源码哈希, 失败时只会导致下次重新编译
+	if sum, err := base.buildSum(); err == nil {
+		_ = buildcache.Store(base.binPath, sum)
+	}
 	return base.binPath
 }
 
+// 读取配置中 data 下的数据库连接, key 为连接名
+func loadDataConnections(layered *conf.Layered) (map[string]conf.DB, error) {
+	conns := make(map[string]conf.DB)
+	v, err := layered.Viper()
+	if err != nil {
+		return nil, err
+	}
+	data := v.Sub("data")
+	if data == nil {
+		return conns, nil
+	}
+	for name := range data.AllSettings() {
+		sub := data.Sub(name)
+		if sub == nil || !sub.IsSet("driver") {
+			continue
+		}
+		var conn conf.DB
+		if err := sub.Unmarshal(&conn); err != nil {
+			return nil, err
+		}
+		conns[name] = conn
+	}
+	return conns, nil
+}
+
+func (base *baseCmd) buildArgs() []string {
+	args := append([]string{"build"}, base.profile.Args()...)
+	return append(args, "-o", base.binPath, base.mainPath)
+}
+
+// 源码以及影响编译结果的参数、环境变量、go版本的哈希
+func (base *baseCmd) buildSum() (string, error) {
+	args := base.buildArgs()
+	for _, key := range buildEnvKeys {
+		args = append(args, fmt.Sprintf("%s=%s", key, os.Getenv(key)))
+	}
+	args = append(args, base.profile.Environ()...)
+	// 与编译时相同的目录和环境变量, go.mod 中的 toolchain 可能切换go版本
+	fd := exec.Command("go", "env", "GOVERSION")
+	fd.Dir = base.workingDir
+	fd.Env = append(os.Environ(), base.profile.Environ()...)
+	goVersion, err := fd.Output()
+	if err != nil {
+		return "", err
+	}
+	args = append(args, strings.TrimSpace(string(goVersion)))
+	return buildcache.Sum(base.workingDir, args)
+}
+
+// 初始化编译配置, 未指定 --profile 时使用 defaultProfile, 为空时不使用任何编译参数
+func (base *baseCmd) initProfile(cmd *cobra.Command, defaultProfile string) {
+	var err error
+	base.profileName = getProfile(cmd)
+	if len(base.profileName) == 0 {
+		base.profileName = defaultProfile
+	}
+	if len(base.profileName) == 0 {
+		return
+	}
+	if base.profile, err = base.Manifest().Build.GetProfile(base.profileName); err != nil {
+		fmt.Printf("🚫 %v\n", err)
+		os.Exit(1)
+		return
+	}
+	return
+}
+
 func (base *baseCmd) InProjectPath() bool {
 	_, err := os.Stat(base.mainPath)
 	if os.IsNotExist(err) {
@@ -128,6 +368,14 @@ type flag struct {
 	usage        string
 }
 
+var (
+	flagForceBuild = flag{"force-build", "", false, "Rebuild the binary even if the sources have not changed"}
+	flagProfile    = flag{"profile", "", "", "Build profile declared in 'aurora.yaml', or one of the built-in profiles: release, debug, race"}
+
+	// 影响编译结果的环境变量
+	buildEnvKeys = []string{"GOOS", "GOARCH", "CGO_ENABLED", "GOFLAGS", "GOEXPERIMENT"}
+)
+
 func getFlags(cmd *cobra.Command, persistent bool) *pflag.FlagSet {
 	flags := cmd.Flags()
 	if persistent {
@@ -135,3 +383,26 @@ func getFlags(cmd *cobra.Command, persistent bool) *pflag.FlagSet {
 	}
 	return flags
 }
+
+func addForceBuildFlag(cmd *cobra.Command, persistent bool) {
+	getFlags(cmd, persistent).Bool(flagForceBuild.name, flagForceBuild.defaultValue.(bool), flagForceBuild.usage)
+}
+
+func getForceBuild(cmd *cobra.Command) bool {
+	var (
+		forceBuild bool
+		err        error
+	)
+	if forceBuild, err = cmd.Flags().GetBool(flagForceBuild.name); err != nil {
+		panic(err)
+	}
+	return forceBuild
+}
+
+func addProfileFlag(cmd *cobra.Command, persistent bool) {
+	getFlags(cmd, persistent).String(flagProfile.name, flagProfile.defaultValue.(string), flagProfile.usage)
+}
+
+func getProfile(cmd *cobra.Command) string {
+	return cmd.Flag(flagProfile.name).Value.String()
+}
@@ -1,13 +1,25 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/conf"
 	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/buildinfo"
+	"github.com/stubborn-gaga-0805/aurora/pkg/release"
 	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 )
 
 type buildCmd struct {
@@ -16,11 +28,38 @@ type buildCmd struct {
 }
 
 type buildFlags struct {
-	flagOutput string
+	flagOutput       string
+	flagPlatforms    []string
+	flagJobs         int
+	flagVersion      string
+	flagPrintVersion bool
+	flagPackage      bool
+}
+
+// buildTarget 编译目标平台, 为空时使用当前平台
+type buildTarget struct {
+	goos   string
+	goarch string
+	output string
+}
+
+type buildResult struct {
+	target   buildTarget
+	size     int64
+	duration time.Duration
+	output   []byte
+	err      error
 }
 
 var (
-	flagOutput = flag{"output", "o", "./bin/server", "custom binaries output directory... "}
+	flagOutput       = flag{"output", "o", "./bin/server", "custom binaries output directory... "}
+	flagPlatforms    = flag{"platform", "", []string{}, "Target platforms (GOOS/GOARCH), output: <output>_<os>_<arch>, eg: linux/amd64,darwin/arm64"}
+	flagJobs         = flag{"jobs", "j", runtime.NumCPU(), "Number of platforms to build in parallel"}
+	flagVersion      = flag{"version", "", "", "Set the version to inject (default: git tag, then 'env.appVersion' in the config file)"}
+	flagPrintVersion = flag{"print-version", "", false, "Print the version information that would be injected and exit"}
+	flagPackage      = flag{"package", "", false, "Package the binaries with 'configs/' and LICENSE into <output dir>/dist, with SHA256SUMS and SBOM"}
+
+	errInvalidPlatform = errors.New("invalid platform, expected <os>/<arch>")
 )
 
 func newBuildCmd() *buildCmd {
@@ -47,16 +86,35 @@ func (build *buildCmd) initBuildRuntime(cmd *cobra.Command) {
 	build.id, _ = os.Hostname()
 	build.env = Env(os.Getenv(consts.OSEnvKey))
 	build.buildFlags = &buildFlags{
-		flagOutput: getOutput(cmd),
+		flagOutput:       getOutput(cmd),
+		flagPlatforms:    getPlatforms(cmd),
+		flagJobs:         getJobs(cmd),
+		flagVersion:      getVersion(cmd),
+		flagPrintVersion: getPrintVersion(cmd),
+		flagPackage:      getPackage(cmd),
 	}
+	build.initProfile(cmd, build.Manifest().Build.BuildProfile())
 	return
 }
 
 func (build *buildCmd) run(args []string) {
 	var (
-		goArgs    = []string{"build", "-ldflags=-s -w"}
+		info      = buildinfo.Collect(build.workingDir, build.flagVersion, build.configAppVersion())
+		vars      = build.Manifest().Build.Variables.Resolve()
+		goArgs    = build.profile.Args(info.LDFlags(buildinfo.Variables(vars))...)
+		mainPaths = make([]string, 0, 2)
 		outputDir = ""
 	)
+	if build.flagPrintVersion {
+		printBuildInfo(info, buildinfo.Variables(vars))
+		return
+	}
+	targets, err := build.targets()
+	if err != nil {
+		fmt.Printf("🚫 Command [%s] execution failed...[%v]\n", build.cmd.Use, err)
+		os.Exit(1)
+		return
+	}
 	if len(build.flagOutput) > 0 {
 		outputDir = filepath.Dir(build.flagOutput)
 		if err := os.MkdirAll(outputDir, fs.ModePerm); err != nil {
@@ -64,7 +122,6 @@ func (build *buildCmd) run(args []string) {
 			os.Exit(1)
 			return
 		}
-		goArgs = append(goArgs, "-o", build.flagOutput)
 	}
 	if len(args) > 0 {
 		absPath, err := build.FilePathToAbs(args[0])
@@ -78,7 +135,7 @@ func (build *buildCmd) run(args []string) {
 			os.Exit(1)
 			return
 		}
-		goArgs = append(goArgs, absPath)
+		mainPaths = append(mainPaths, absPath)
 	} else {
 		// 如果没有指定main的位置就检查是否在项目目录下
 		if !build.InProjectPath() {
@@ -86,24 +143,332 @@ func (build *buildCmd) run(args []string) {
 			os.Exit(1)
 			return
 		}
-		goArgs = append(goArgs, build.mainPath)
+		mainPaths = append(mainPaths, build.mainPath)
 	}
-	goArgs = append(goArgs, build.mainPath)
-	fd := exec.Command("go", goArgs...)
-	fd.Stdout = os.Stdout
-	fd.Stderr = os.Stderr
-	if err := fd.Run(); err != nil {
-		fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", build.cmd.Use, err)
-		os.Exit(1)
-		return
+	fmt.Printf("🧰 Build profile: [%s]\n", build.profileName)
+
+	results := build.buildTargets(targets, goArgs, mainPaths)
+	if len(build.flagPlatforms) > 0 {
+		printBuildSummary(results)
+	}
+	for _, result := range results {
+		if result.err != nil {
+			if len(build.flagPlatforms) == 0 {
+				fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", build.cmd.Use, result.err)
+			}
+			os.Exit(1)
+			return
+		}
+	}
+	if build.flagPackage {
+		if err := build.packageTargets(targets, info); err != nil {
+			fmt.Printf("🚫 Failed to package release artifacts...[%v]\n", err)
+			os.Exit(1)
+			return
+		}
 	}
 	return
 }
 
+// 打包所有目标平台的二进制文件、配置文件和LICENSE, 并生成 SHA256SUMS 和 SBOM
+func (build *buildCmd) packageTargets(targets []buildTarget, info *buildinfo.Info) error {
+	var (
+		artifacts = make([]string, 0, len(targets)*2)
+		version   = strings.NewReplacer("/", "-", " ", "-").Replace(info.Version)
+		extras    = make([]release.File, 0, 2)
+	)
+	modulePath, err := helpers.ModulePath(filepath.Join(build.workingDir, "go.mod"))
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"configs", "LICENSE"} {
+		path := filepath.Join(build.workingDir, name)
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("⚠️ [%s] not found, skipped...\n", name)
+			continue
+		}
+		extras = append(extras, release.File{Name: name, Path: path})
+	}
+	for _, target := range targets {
+		if len(target.output) == 0 {
+			return errors.New("the output path is required to package release artifacts")
+		}
+		var (
+			goos, goarch, _ = strings.Cut(target.platform(), "/")
+			distDir         = filepath.Join(filepath.Dir(target.output), "dist")
+			baseName        = fmt.Sprintf("%s_%s_%s_%s", filepath.Base(modulePath), version, goos, goarch)
+			format          = release.FormatFor(goos)
+			binName         = "server"
+		)
+		if goos == "windows" {
+			binName += ".exe"
+		}
+		if err = os.MkdirAll(distDir, fs.ModePerm); err != nil {
+			return err
+		}
+		archive := filepath.Join(distDir, fmt.Sprintf("%s.%s", baseName, format))
+		files := append([]release.File{{Name: binName, Path: target.output}}, extras...)
+		if err = release.Archive(archive, format, files); err != nil {
+			return err
+		}
+		fmt.Printf("📦 %s\n", archive)
+		bi, err := release.ReadBuildInfo(target.output)
+		if err != nil {
+			return err
+		}
+		sbom := filepath.Join(distDir, baseName+".cdx.json")
+		if err = release.WriteSBOM(sbom, bi, modulePath, info.Version, consts.Version); err != nil {
+			return err
+		}
+		fmt.Printf("📋 %s\n", sbom)
+		artifacts = append(artifacts, archive, sbom)
+	}
+	checksums := filepath.Join(filepath.Dir(artifacts[0]), release.ChecksumFile)
+	if err = release.WriteChecksums(checksums, artifacts); err != nil {
+		return err
+	}
+	fmt.Printf("🔐 %s\n", checksums)
+	return nil
+}
+
+// 解析 --platform 得到编译目标, 未指定时只编译当前平台
+func (build *buildCmd) targets() ([]buildTarget, error) {
+	if len(build.flagPlatforms) == 0 {
+		return []buildTarget{{output: build.flagOutput}}, nil
+	}
+	var (
+		output  = build.flagOutput
+		targets = make([]buildTarget, 0, len(build.flagPlatforms))
+	)
+	if len(output) == 0 {
+		output = flagOutput.defaultValue.(string)
+	}
+	for _, platform := range build.flagPlatforms {
+		goos, goarch, ok := strings.Cut(strings.TrimSpace(platform), "/")
+		if !ok || len(goos) == 0 || len(goarch) == 0 {
+			return nil, fmt.Errorf("%w: %q", errInvalidPlatform, platform)
+		}
+		target := buildTarget{
+			goos:   goos,
+			goarch: goarch,
+			output: fmt.Sprintf("%s_%s_%s", output, goos, goarch),
+		}
+		if goos == "windows" {
+			target.output += ".exe"
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// 使用有限的并发数编译所有目标平台, 结果的顺序与目标平台一致
+func (build *buildCmd) buildTargets(targets []buildTarget, goArgs, mainPaths []string) []buildResult {
+	var (
+		wg      sync.WaitGroup
+		jobs    = build.flagJobs
+		queue   = make(chan int)
+		results = make([]buildResult, len(targets))
+	)
+	if jobs <= 0 {
+		jobs = 1
+	}
+	for i := 0; i < jobs && i < len(targets); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range queue {
+				results[idx] = build.buildTarget(targets[idx], goArgs, mainPaths, len(targets) > 1)
+			}
+		}()
+	}
+	for idx := range targets {
+		queue <- idx
+	}
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+func (build *buildCmd) buildTarget(target buildTarget, goArgs, mainPaths []string, captureOutput bool) (result buildResult) {
+	var (
+		start = time.Now()
+		args  = append([]string{"build"}, goArgs...)
+	)
+	result.target = target
+	if len(target.output) > 0 {
+		args = append(args, "-o", target.output)
+	}
+	args = append(args, mainPaths...)
+	fd := exec.Command("go", args...)
+	fd.Env = append(os.Environ(), build.profile.Environ()...)
+	if len(target.goos) > 0 {
+		fd.Env = append(fd.Env, "GOOS="+target.goos, "GOARCH="+target.goarch)
+		// 编译配置没有指定CGO时, 交叉编译禁用CGO
+		if build.profile.CGO == nil && (target.goos != runtime.GOOS || target.goarch != runtime.GOARCH) {
+			fd.Env = append(fd.Env, "CGO_ENABLED=0")
+		}
+		fmt.Printf("🔨 Building [%s]...\n", color.BlueString(target.platform()))
+	}
+	// 并行编译时缓存输出, 避免日志交错
+	var buf bytes.Buffer
+	if captureOutput {
+		fd.Stdout = &buf
+		fd.Stderr = &buf
+	} else {
+		fd.Stdout = os.Stdout
+		fd.Stderr = os.Stderr
+	}
+	result.err = fd.Run()
+	result.duration = time.Since(start)
+	result.output = buf.Bytes()
+	if result.err == nil && len(target.output) > 0 {
+		if info, err := os.Stat(target.output); err == nil {
+			result.size = info.Size()
+		}
+	}
+	return result
+}
+
+// 配置文件中的应用版本 (env.appVersion)
+func (build *buildCmd) configAppVersion() string {
+	var (
+		env     = build.env
+		configs *conf.App
+	)
+	if len(env) == 0 {
+		env = defaultRuntimeEnv()
+	}
+	layered, err := conf.LoadLayered(conf.LoadOptions{Dir: filepath.Join(build.workingDir, "configs"), Env: env.ToString(), EnvFile: env.ConfigFile()})
+	if err != nil {
+		return ""
+	}
+	if err = layered.Unmarshal(&configs); err != nil || configs == nil {
+		return ""
+	}
+	return configs.Env.AppVersion
+}
+
+func printBuildInfo(info *buildinfo.Info, vars buildinfo.Variables) {
+	var (
+		w    = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		rows = [][3]string{
+			{"Version", fmt.Sprintf("%s (%s)", info.Version, info.VersionSource), vars.Version},
+			{"Commit", info.Commit, vars.Commit},
+			{"Dirty", fmt.Sprintf("%t", info.Dirty), vars.Dirty},
+			{"BuildTime", info.BuildTime, vars.BuildTime},
+			{"GoVersion", info.GoVersion, vars.GoVersion},
+		}
+	)
+	_, _ = fmt.Fprintln(w, "NAME\tVALUE\tVARIABLE")
+	for _, row := range rows {
+		variable := row[2]
+		if len(variable) == 0 {
+			variable = color.HiBlackString("(disabled)")
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", row[0], color.GreenString(row[1]), variable)
+	}
+	_ = w.Flush()
+	fmt.Printf("\n-ldflags=\"%s\"\n", strings.Join(info.LDFlags(vars), " "))
+}
+
+func (t buildTarget) platform() string {
+	if len(t.goos) == 0 {
+		return fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	return fmt.Sprintf("%s/%s", t.goos, t.goarch)
+}
+
+func printBuildSummary(results []buildResult) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PLATFORM\tOUTPUT\tSIZE\tDURATION\tSTATUS")
+	for _, result := range results {
+		status := color.GreenString("ok")
+		if result.err != nil {
+			status = color.RedString("failed")
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.target.platform(), result.target.output, formatSize(result.size), result.duration.Round(time.Millisecond), status)
+	}
+	_ = w.Flush()
+	for _, result := range results {
+		if result.err != nil {
+			fmt.Printf("\n🚫 [%s] build failed...[%v]\n%s", result.target.platform(), result.err, result.output)
+		}
+	}
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 func addBuildRuntimeFlag(cmd *cobra.Command, persistent bool) {
 	getFlags(cmd, persistent).StringP(flagOutput.name, flagOutput.shortName, flagOutput.defaultValue.(string), flagOutput.usage)
+	getFlags(cmd, persistent).StringSlice(flagPlatforms.name, flagPlatforms.defaultValue.([]string), flagPlatforms.usage)
+	getFlags(cmd, persistent).IntP(flagJobs.name, flagJobs.shortName, flagJobs.defaultValue.(int), flagJobs.usage)
+	getFlags(cmd, persistent).String(flagVersion.name, flagVersion.defaultValue.(string), flagVersion.usage)
+	getFlags(cmd, persistent).Bool(flagPrintVersion.name, flagPrintVersion.defaultValue.(bool), flagPrintVersion.usage)
+	getFlags(cmd, persistent).Bool(flagPackage.name, flagPackage.defaultValue.(bool), flagPackage.usage)
+	addProfileFlag(cmd, persistent)
 }
 
 func getOutput(cmd *cobra.Command) string {
 	return cmd.Flag(flagOutput.name).Value.String()
 }
+
+func getPlatforms(cmd *cobra.Command) []string {
+	var (
+		platforms []string
+		err       error
+	)
+	if platforms, err = cmd.Flags().GetStringSlice(flagPlatforms.name); err != nil {
+		panic(err)
+	}
+	return platforms
+}
+
+func getJobs(cmd *cobra.Command) int {
+	var (
+		jobs int
+		err  error
+	)
+	if jobs, err = cmd.Flags().GetInt(flagJobs.name); err != nil {
+		panic(err)
+	}
+	return jobs
+}
+
+func getVersion(cmd *cobra.Command) string {
+	return cmd.Flag(flagVersion.name).Value.String()
+}
+
+func getPrintVersion(cmd *cobra.Command) bool {
+	var (
+		printVersion bool
+		err          error
+	)
+	if printVersion, err = cmd.Flags().GetBool(flagPrintVersion.name); err != nil {
+		panic(err)
+	}
+	return printVersion
+}
+
+func getPackage(cmd *cobra.Command) bool {
+	var (
+		pack bool
+		err  error
+	)
+	if pack, err = cmd.Flags().GetBool(flagPackage.name); err != nil {
+		panic(err)
+	}
+	return pack
+}
@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/conf"
+	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/stubborn-gaga-0805/aurora/pkg/config"
+	"github.com/stubborn-gaga-0805/aurora/pkg/kube"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+type configCmd struct {
+	*baseCmd
+}
+
+type configValidateCmd struct {
+	*baseCmd
+}
+
+type configSchemaCmd struct {
+	*baseCmd
+}
+
+type configShowCmd struct {
+	*baseCmd
+}
+
+type configDiffCmd struct {
+	*baseCmd
+}
+
+var (
+	flagConfigEnv     = flag{"env", "e", "", "The environment of the config file to check (default: all environments declared in aurora.yaml)"}
+	flagConfigOutput  = flag{"output", "o", "", "Write the schema to the file instead of stdout, eg: configs/config.schema.json"}
+	flagConfigShowEnv = flag{"env", "e", "", "The environment of the config (default: $RUNTIME_ENV or the first environment declared in aurora.yaml)"}
+	flagConfigReveal  = flag{"reveal", "", false, "Show the passwords and secrets instead of masking them"}
+	flagConfigSet     = flag{"set", "", []string{}, "Override a config key, can be repeated, eg: --set data.db.addr=127.0.0.1:3306"}
+	flagDiffIgnore    = flag{"ignore-values", "", false, "Only report the keys that are missing, extra or of a different type"}
+	flagDiffAllow     = flag{"allow", "", []string{}, "Keys expected to differ, can be repeated and use wildcards, eg: --allow 'data.*.addr' (merged with config.diffAllow in aurora.yaml)"}
+	flagDiffJSON      = flag{"json", "", false, "Print the differences as JSON"}
+)
+
+func newConfigCmd() *configCmd {
+	cc := &configCmd{newBaseCmd()}
+	cc.cmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and check the config files of the project",
+		Long:  "💡 Inspect and check the config files of the project, eg: aurora config show --env prod",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Usage(); err != nil {
+				panic(err)
+			}
+		},
+	}
+	cc.addCommands(
+		newConfigValidateCmd(),
+		newConfigSchemaCmd(),
+		newConfigShowCmd(),
+		newConfigDiffCmd(),
+	)
+
+	return cc
+}
+
+func newConfigValidateCmd() *configValidateCmd {
+	cv := &configValidateCmd{newBaseCmd()}
+	cv.cmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the config files against the config structs of aurora",
+		Long:  "💡 Validate the config files, reject unknown keys, unsupported values, invalid durations and missing required keys, eg: aurora config validate -e prod",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cv.run(cmd.Flag(flagConfigEnv.name).Value.String())
+		},
+	}
+	getFlags(cv.cmd, false).StringP(flagConfigEnv.name, flagConfigEnv.shortName, flagConfigEnv.defaultValue.(string), flagConfigEnv.usage)
+	registerEnvCompletion(cv.cmd, flagConfigEnv.name)
+
+	return cv
+}
+
+func (cv *configValidateCmd) run(env string) {
+	targets, err := cv.configTargets(env)
+	if err != nil {
+		fmt.Printf("🚫 %v\n", err)
+		os.Exit(1)
+		return
+	}
+	var invalid int
+	for _, target := range targets {
+		issues, err := config.ValidateFiles(target.files...)
+		if err != nil {
+			fmt.Printf("🚫 Failed to read the config file...[%v]\n", err)
+			os.Exit(1)
+			return
+		}
+		label := strings.Join(cv.relFiles(target.files), " + ")
+		if len(target.env) > 0 {
+			label = fmt.Sprintf("%s (%s)", target.env, label)
+		}
+		if len(issues) == 0 {
+			fmt.Printf("✅ %s\n", label)
+			continue
+		}
+		invalid++
+		fmt.Printf("❌ %s\n", label)
+		for _, issue := range issues {
+			issue.File = cv.relFiles([]string{issue.File})[0]
+			fmt.Printf("   %s\n", color.RedString(issue.String()))
+		}
+	}
+	if invalid > 0 {
+		fmt.Printf("\n🚫 %d of %d configs are invalid\n", invalid, len(targets))
+		os.Exit(1)
+		return
+	}
+	return
+}
+
+// configTarget 一个环境合并的配置文件
+type configTarget struct {
+	env   string
+	files []string
+}
+
+// configTargets 需要校验的配置, 未指定环境时校验项目声明的环境中存在配置文件的环境 (与 config.yaml 合并),
+// 只有 config.yaml 时单独校验
+func (cv *configValidateCmd) configTargets(env string) ([]configTarget, error) {
+	dir := filepath.Join(cv.workingDir, "configs")
+	if len(env) != 0 {
+		files := conf.ConfigFiles(dir, Env(env).ConfigFile())
+		if len(files) == 0 {
+			return nil, fmt.Errorf("the config file of [%s] is not found in [%s]", env, dir)
+		}
+		return []configTarget{{env: env, files: files}}, nil
+	}
+	var err error
+	targets := make([]configTarget, 0)
+	for _, e := range supportsRuntimeEnvs() {
+		if _, err = os.Stat(filepath.Join(dir, e.ConfigFile())); err != nil {
+			continue
+		}
+		targets = append(targets, configTarget{env: e.ToString(), files: conf.ConfigFiles(dir, e.ConfigFile())})
+	}
+	if base := filepath.Join(dir, conf.BaseConfigFile); len(targets) == 0 {
+		if _, err = os.Stat(base); err == nil {
+			targets = append(targets, configTarget{files: []string{base}})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no config files found in [%s], please run it in the project root directory", dir)
+	}
+	return targets, nil
+}
+
+func newConfigSchemaCmd() *configSchemaCmd {
+	cs := &configSchemaCmd{newBaseCmd()}
+	cs.cmd = &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the config files",
+		Long:  "💡 Print the JSON Schema of the config files for editor completion, eg: aurora config schema -o configs/config.schema.json",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cs.run(cmd.Flag(flagConfigOutput.name).Value.String())
+		},
+	}
+	getFlags(cs.cmd, false).StringP(flagConfigOutput.name, flagConfigOutput.shortName, flagConfigOutput.defaultValue.(string), flagConfigOutput.usage)
+
+	return cs
+}
+
+func (cs *configSchemaCmd) run(output string) {
+	content, err := json.MarshalIndent(config.Schema(), "", "  ")
+	if err != nil {
+		fmt.Printf("🚫 Failed to generate the schema...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	content = append(content, '\n')
+	if len(output) == 0 {
+		_, _ = os.Stdout.Write(content)
+		return
+	}
+	if err = os.WriteFile(output, content, 0644); err != nil {
+		fmt.Printf("🚫 Failed to write the schema...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	fmt.Printf("✅ The schema has been written to [%s], add %s to the top of the config files to enable it in the editor\n", output, color.GreenString("# yaml-language-server: $schema=%s", schemaRef(output)))
+	return
+}
+
+// schemaRef 配置文件中引用 schema 的路径, 配置文件都在 configs 目录下
+func schemaRef(output string) string {
+	abs, err := filepath.Abs(output)
+	if err != nil {
+		return output
+	}
+	wd, _ := os.Getwd()
+	rel, err := filepath.Rel(filepath.Join(wd, "configs"), abs)
+	if err != nil {
+		return output
+	}
+	if !strings.HasPrefix(rel, ".") {
+		rel = "./" + rel
+	}
+	return filepath.ToSlash(rel)
+}
+
+func newConfigShowCmd() *configShowCmd {
+	cs := &configShowCmd{newBaseCmd()}
+	cs.cmd = &cobra.Command{
+		Use:   "show",
+		Short: "Print the merged config and where each key comes from",
+		Long:  "💡 Print the config merged from config.yaml, config.<env>.yaml, AURORA_* env vars and --set, with the origin of each key, eg: aurora config show -e prod",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			reveal, _ := cmd.Flags().GetBool(flagConfigReveal.name)
+			cs.initConfigShowRuntime(cmd)
+			cs.run(reveal)
+		},
+	}
+	getFlags(cs.cmd, false).StringP(flagConfigShowEnv.name, flagConfigShowEnv.shortName, flagConfigShowEnv.defaultValue.(string), flagConfigShowEnv.usage)
+	registerEnvCompletion(cs.cmd, flagConfigShowEnv.name)
+	getFlags(cs.cmd, false).Bool(flagConfigReveal.name, flagConfigReveal.defaultValue.(bool), flagConfigReveal.usage)
+	addConfigSetFlag(cs.cmd, false)
+	addEnvFileFlag(cs.cmd, false)
+
+	return cs
+}
+
+func (cs *configShowCmd) initConfigShowRuntime(cmd *cobra.Command) {
+	cs.env = Env(cmd.Flag(flagConfigShowEnv.name).Value.String())
+	if len(cs.env) == 0 {
+		cs.env = Env(os.Getenv(consts.OSEnvKey))
+	}
+	if len(cs.env) == 0 {
+		cs.env = defaultRuntimeEnv()
+	}
+	cs.sets = getConfigSets(cmd)
+	cs.initEnvironment(cmd)
+	return
+}
+
+func (cs *configShowCmd) run(reveal bool) {
+	layered, err := cs.loadConfig()
+	if err != nil {
+		fmt.Printf("🚫 Failed to load the config...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	cs.annotate(layered, layered.Node(), nil, reveal)
+	content, err := layered.YAML()
+	if err != nil {
+		fmt.Printf("🚫 Failed to print the config...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	fmt.Println(color.HiBlackString("# env: %s, merged from: %s", cs.env, strings.Join(cs.relFiles(layered.Files), " + ")))
+	fmt.Print(string(content))
+	return
+}
+
+// annotate 在每个配置项后面注释它的来源, 并隐藏密码
+func (cs *configShowCmd) annotate(layered *conf.Layered, node *yaml.Node, path []string, reveal bool) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var (
+			key, value = node.Content[i], node.Content[i+1]
+			keyPath    = append(append([]string{}, path...), key.Value)
+		)
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			cs.annotate(layered, value, keyPath, reveal)
+			continue
+		}
+		origin, ok := layered.Origin(strings.Join(keyPath, "."))
+		if !ok {
+			continue
+		}
+		if origin.Kind == conf.OriginFile {
+			origin.Name = cs.relFiles([]string{origin.Name})[0]
+		}
+		if value.Kind != yaml.ScalarNode {
+			key.LineComment = origin.String()
+			continue
+		}
+		if !reveal && kube.IsSecretKey(key.Value) && len(value.Value) > 0 {
+			value.Value, value.Tag, value.Style = "******", "!!str", 0
+		}
+		value.LineComment = origin.String()
+	}
+}
+
+func newConfigDiffCmd() *configDiffCmd {
+	cd := &configDiffCmd{newBaseCmd()}
+	cd.cmd = &cobra.Command{
+		Use:   "diff <env> <env>",
+		Short: "Compare the configs of two environments",
+		Long:  "💡 Report the keys that are missing, extra or of a different type between two environments, exit with 1 if there are differences, eg: aurora config diff dev prod --ignore-values",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) >= 2 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeEnvs(cmd, args, toComplete)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			cd.run(cmd, Env(args[0]), Env(args[1]))
+		},
+	}
+	getFlags(cd.cmd, false).Bool(flagDiffIgnore.name, flagDiffIgnore.defaultValue.(bool), flagDiffIgnore.usage)
+	getFlags(cd.cmd, false).StringArray(flagDiffAllow.name, flagDiffAllow.defaultValue.([]string), flagDiffAllow.usage)
+	getFlags(cd.cmd, false).Bool(flagDiffJSON.name, flagDiffJSON.defaultValue.(bool), flagDiffJSON.usage)
+
+	return cd
+}
+
+// run 有差异时退出码为1, 无法比较时为2
+func (cd *configDiffCmd) run(cmd *cobra.Command, left, right Env) {
+	var (
+		opts      = config.DiffOptions{Allow: append([]string{}, cd.Manifest().Config.DiffAllow...)}
+		allow, _  = cmd.Flags().GetStringArray(flagDiffAllow.name)
+		asJSON, _ = cmd.Flags().GetBool(flagDiffJSON.name)
+		configs   = make([]*conf.Layered, 2)
+		dir       = filepath.Join(cd.workingDir, "configs")
+		err       error
+	)
+	opts.IgnoreValues, _ = cmd.Flags().GetBool(flagDiffIgnore.name)
+	opts.Allow = append(opts.Allow, allow...)
+	for i, env := range []Env{left, right} {
+		if !env.Check() {
+			fmt.Printf("🚫 Unsupported operating environment [%s], the supported environments are: %s\n", env, joinEnvs(supportsRuntimeEnvs()))
+			os.Exit(2)
+			return
+		}
+		if configs[i], err = conf.LoadLayered(conf.LoadOptions{Dir: dir, Env: env.ToString(), EnvFile: env.ConfigFile()}); err != nil {
+			fmt.Printf("🚫 Failed to load the config of [%s]...[%v]\n", env, err)
+			os.Exit(2)
+			return
+		}
+	}
+	diffs := config.Diff(configs[0].Node(), configs[1].Node(), opts)
+	if asJSON {
+		content, _ := json.MarshalIndent(diffs, "", "  ")
+		fmt.Println(string(content))
+	} else {
+		cd.printDiffs(diffs, left, right, opts.IgnoreValues)
+	}
+	if len(diffs) > 0 {
+		os.Exit(1)
+		return
+	}
+	return
+}
+
+func (cd *configDiffCmd) printDiffs(diffs []config.Difference, left, right Env, ignoreValues bool) {
+	if len(diffs) == 0 {
+		if ignoreValues {
+			fmt.Printf("✅ [%s] and [%s] have the same keys\n", left, right)
+		} else {
+			fmt.Printf("✅ [%s] and [%s] have the same config\n", left, right)
+		}
+		return
+	}
+	var (
+		w      = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		counts = make(map[string]int)
+	)
+	_, _ = fmt.Fprintf(w, "KIND\tKEY\t%s\t%s\n", strings.ToUpper(left.ToString()), strings.ToUpper(right.ToString()))
+	for _, diff := range diffs {
+		counts[diff.Kind]++
+		l, r := lo.Ternary(len(diff.LeftType) == 0, "-", diff.Left), lo.Ternary(len(diff.RightType) == 0, "-", diff.Right)
+		if diff.Kind == config.DiffType {
+			l, r = fmt.Sprintf("%s (%s)", l, diff.LeftType), fmt.Sprintf("%s (%s)", r, diff.RightType)
+		}
+		kind := diff.Kind
+		switch diff.Kind {
+		case config.DiffMissing, config.DiffType:
+			kind = color.RedString(kind)
+		case config.DiffExtra:
+			kind = color.YellowString(kind)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", kind, diff.Key, l, r)
+	}
+	_ = w.Flush()
+	fmt.Printf("\n🚫 %d differences: %d missing in [%s], %d only in [%s], %d of a different type, %d of a different value\n",
+		len(diffs), counts[config.DiffMissing], right, counts[config.DiffExtra], right, counts[config.DiffType], counts[config.DiffValue])
+	fmt.Printf("💡 Use --%s to compare the keys only, or --%s to allow the keys expected to differ\n", flagDiffIgnore.name, flagDiffAllow.name)
+	return
+}
+
+func addConfigSetFlag(cmd *cobra.Command, persistent bool) {
+	getFlags(cmd, persistent).StringArray(flagConfigSet.name, flagConfigSet.defaultValue.([]string), flagConfigSet.usage)
+}
+
+func getConfigSets(cmd *cobra.Command) []string {
+	var (
+		sets []string
+		err  error
+	)
+	if sets, err = cmd.Flags().GetStringArray(flagConfigSet.name); err != nil {
+		panic(err)
+	}
+	return sets
+}
@@ -1,34 +1,54 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/fatih/color"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/manifest"
+	"github.com/stubborn-gaga-0805/aurora/pkg/templates"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+	"io"
 	"io/fs"
-	"io/ioutil"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
-var replaceDirs = []string{"api", "cmd", "configs", "internal", "pkg"}
-
 type createCmd struct {
 	*baseCmd
 	*createFlags
 
 	projectName string
 	projectPath string
+	module      string
 	branch      string
-	sshPath     string
-	workingDir  string
+	template    templates.Template
+	commit      string
+	variables   map[string]string
+	hooks       []templates.Hook
+	remote      string
+	// initialCommit 重新初始化GIT历史并提交 "Initial commit"
+	initialCommit bool
+	push          bool
+	force         bool
+	sshPath       string
+	workingDir    string
 
 	done chan error
 }
@@ -36,11 +56,49 @@ type createCmd struct {
 type createFlags struct {
 	flagProjectPath string
 	flagIsDemo      bool
+	noInput         bool
+	yes             bool
+	offline         bool
+	answers         createAnswers
+}
+
+// createAnswers 创建项目需要的所有答案, 可以通过 --from-file 从YAML文件中读取
+type createAnswers struct {
+	Name     string `yaml:"name"`
+	Path     string `yaml:"path"`
+	Module   string `yaml:"module"`
+	Branch   string `yaml:"branch"`
+	Template string `yaml:"template"`
+	Demo     *bool  `yaml:"demo"`
+	Force    bool   `yaml:"force"`
+	// InitialCommit 使用单个 "Initial commit" 替换模板的GIT历史
+	InitialCommit bool   `yaml:"initial_commit"`
+	Remote        string `yaml:"remote"`
+	Push          bool   `yaml:"push"`
+	// Variables 模板 (aurora-template.yaml) 中声明的变量
+	Variables map[string]string `yaml:"variables"`
 }
 
+// stageCleanupGrace 中断后等待正在执行的命令退出的时间, 之后删除临时目录
+const stageCleanupGrace = 5 * time.Second
+
 var (
 	flagProjectPath = flag{"path", "p", "", `project path`}
 	flagIsDemo      = flag{"with.demo", "", false, `whether to create a 'demo' project`}
+	flagYes         = flag{"yes", "y", false, `do not prompt, use the default value for every answer that is not given`}
+	flagNoInput     = flag{"no-input", "", false, `alias of --yes`}
+	flagModule      = flag{"module", "m", "", `the go module path of the project, eg: github.com/org/app (default: the project name)`}
+	flagBranch      = flag{"branch", "b", "", `the branch of the template repository (default: depends on --with.demo)`}
+	flagForce       = flag{"force", "f", false, `overwrite the target directory if it already exists`}
+	flagFromFile    = flag{"from-file", "", "", `read the answers from a YAML file (keys: name, path, module, branch, template, demo, force, initial_commit, remote, push, variables)`}
+	flagVar         = flag{"var", "", []string{}, `set a variable declared in the template's aurora-template.yaml, eg: --var HttpPort=8080 (repeatable)`}
+	flagOffline     = flag{"offline", "", false, `create the project from the local template cache without network access`}
+	flagInitCommit  = flag{"initial-commit", "", false, `replace the git history of the template with a single "Initial commit" authored from your git config`}
+	flagRemote      = flag{"remote", "", "", `add the git remote 'origin' with the url, eg: git@github.com:org/app.git`}
+	flagPush        = flag{"push", "", false, `push the 'main' branch to the remote after the project is created (implies --initial-commit)`}
+	flagTemplate    = flag{"template", "t", "", `the template to create from: a name in the template registry, a git url, a local directory or a .tar.gz archive (default: prepare2go)`}
+
+	errNoTTY = errors.New("stdin is not a terminal")
 )
 
 func newCreateCmd() *createCmd {
@@ -51,10 +109,11 @@ func newCreateCmd() *createCmd {
 		Use:     "create",
 		Aliases: []string{},
 		Short:   "Create a new project",
-		Long:    `💡 Create a new project, eg: aurora create my-app`,
+		Long:    `💡 Create a new project, eg: aurora create my-app --module github.com/org/my-app --yes`,
+		Args:    cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			create.initCreateRuntime(cmd)
-			create.run(args)
+			create.initCreateRuntime(cmd, args)
+			create.run()
 		},
 	}
 	addCreateRuntimeFlag(create.cmd, true)
@@ -62,12 +121,15 @@ func newCreateCmd() *createCmd {
 	return create
 }
 
-func (create *createCmd) initCreateRuntime(cmd *cobra.Command) {
+func (create *createCmd) initCreateRuntime(cmd *cobra.Command, args []string) {
 	create.id, _ = os.Hostname()
 	create.env = Env(os.Getenv(consts.OSEnvKey))
 	create.createFlags = &createFlags{
 		flagProjectPath: getProjectPath(cmd),
 		flagIsDemo:      getIsDemo(cmd),
+		noInput:         getNoInput(cmd),
+		yes:             getNoInput(cmd),
+		offline:         getOffline(cmd),
 	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -77,164 +139,555 @@ func (create *createCmd) initCreateRuntime(cmd *cobra.Command) {
 	if create.workingDir, err = os.Getwd(); err != nil {
 		panic(err)
 	}
+
+	// 答案文件中的答案会被显式设置的参数覆盖, 指定答案文件时不再提示输入
+	if file := cmd.Flag(flagFromFile.name).Value.String(); len(file) > 0 {
+		if create.answers, err = readCreateAnswers(file); err != nil {
+			fmt.Printf("🚫 Failed to read the answers file [%s]...[%v]\n", file, err)
+			os.Exit(1)
+			return
+		}
+		create.noInput = true
+	}
+	if len(args) > 0 {
+		create.answers.Name = args[0]
+	}
+	if cmd.Flag(flagProjectPath.name).Changed {
+		create.answers.Path = create.flagProjectPath
+	}
+	if cmd.Flag(flagIsDemo.name).Changed {
+		create.answers.Demo = &create.flagIsDemo
+	}
+	if cmd.Flag(flagModule.name).Changed {
+		create.answers.Module = cmd.Flag(flagModule.name).Value.String()
+	}
+	if cmd.Flag(flagBranch.name).Changed {
+		create.answers.Branch = cmd.Flag(flagBranch.name).Value.String()
+	}
+	if cmd.Flag(flagForce.name).Changed {
+		create.answers.Force = getCreateForce(cmd)
+	}
+	if cmd.Flag(flagTemplate.name).Changed {
+		create.answers.Template = cmd.Flag(flagTemplate.name).Value.String()
+	}
+	if cmd.Flag(flagInitCommit.name).Changed {
+		create.answers.InitialCommit, _ = cmd.Flags().GetBool(flagInitCommit.name)
+	}
+	if cmd.Flag(flagRemote.name).Changed {
+		create.answers.Remote = cmd.Flag(flagRemote.name).Value.String()
+	}
+	if cmd.Flag(flagPush.name).Changed {
+		create.answers.Push, _ = cmd.Flags().GetBool(flagPush.name)
+	}
+	if create.answers.Push && len(create.answers.Remote) == 0 {
+		fmt.Printf("🚫 --%s requires --%s <url>...\n", flagPush.name, flagRemote.name)
+		os.Exit(1)
+		return
+	}
+	vars, err := cmd.Flags().GetStringArray(flagVar.name)
+	if err != nil {
+		panic(err)
+	}
+	for _, kv := range vars {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || len(name) == 0 {
+			fmt.Printf("🚫 Invalid --%s [%s], expected NAME=VALUE...\n", flagVar.name, kv)
+			os.Exit(1)
+			return
+		}
+		if create.answers.Variables == nil {
+			create.answers.Variables = make(map[string]string)
+		}
+		create.answers.Variables[name] = value
+	}
+	if create.template, err = resolveTemplate(create.answers.Template); err != nil {
+		fmt.Printf("🚫 %v\n", err)
+		os.Exit(1)
+		return
+	}
 	return
 }
 
-func (create *createCmd) run(args []string) {
+func (create *createCmd) run() {
 	var (
-		err         error
-		projectName string
-		workingDir  = create.workingDir
+		err     error
+		answers = create.answers
 	)
 
 	// 检查项目名
-	if len(args) == 0 {
+	if len(answers.Name) == 0 {
 		promptName := &survey.Input{
 			Message: "enter a name for the project:",
 		}
-		if err = survey.AskOne(promptName, &projectName, survey.WithIcons(func(icons *survey.IconSet) {
+		if err = create.ask("the project name", promptName, &answers.Name, survey.WithIcons(func(icons *survey.IconSet) {
 			icons.Question.Text = "🛠"
 			icons.Question.Format = "blue+b"
 			icons.Error.Text = "❌"
 		}), survey.WithValidator(survey.Required)); err != nil {
-			fmt.Println("🚧 Stopped...something went wrong")
+			create.stopped(err)
+			return
+		}
+		if len(answers.Name) == 0 {
+			fmt.Println("🚫 The project name is required, eg: aurora create my-app --yes")
+			os.Exit(1)
 			return
 		}
-	} else {
-		projectName = args[0]
 	}
 	// 检查是否指定了路径
-	if len(create.flagProjectPath) == 0 {
+	if len(answers.Path) == 0 {
+		answers.Path = create.workingDir
 		promptPath := &survey.Input{
 			Message: "Please enter the project path:",
-			Default: fmt.Sprintf("default: %s/%s", workingDir, projectName),
+			Default: create.workingDir,
 		}
-		if err = survey.AskOne(promptPath, &workingDir, survey.WithIcons(func(icons *survey.IconSet) {
+		if err = create.ask("--"+flagProjectPath.name, promptPath, &answers.Path, survey.WithIcons(func(icons *survey.IconSet) {
 			icons.Question.Text = "📁"
 			icons.Question.Format = "blue+b"
 		})); err != nil {
-			fmt.Println("🚧 Stopped...something went wrong")
+			create.stopped(err)
 			return
 		}
-		workingDir = strings.ReplaceAll(workingDir, "默认: ", "")
-		create.projectPath = filepath.Join(workingDir, projectName)
 	}
-	create.projectName, create.projectPath = parseProjectParams(projectName, create.flagProjectPath)
-	// 是否指定为demo
-	create.branch = consts.BranchProject
-	if !create.flagIsDemo {
+	create.projectName, create.projectPath = parseProjectParams(answers.Name, answers.Path)
+	// 是否指定为demo, 指定了分支或使用其他模板时不再询问
+	if answers.Demo == nil && len(answers.Branch) == 0 && create.template.Builtin() {
+		answers.Demo = new(bool)
 		promptDemo := &survey.Confirm{
 			Message: "Whether to create 'demo' code?",
 			Help:    "The Demo project comes with framework sample code, please do not create a Demo in the production environment",
 			Default: false,
 		}
-		if err = survey.AskOne(promptDemo, &create.flagIsDemo, survey.WithIcons(func(icons *survey.IconSet) {
+		if err = create.ask("--"+flagIsDemo.name, promptDemo, answers.Demo, survey.WithIcons(func(icons *survey.IconSet) {
 			icons.Question.Text = "💡"
 			icons.Question.Format = "blue+b"
 		})); err != nil {
-			fmt.Println("🚧 Stopped...something went wrong")
+			create.stopped(err)
 			return
 		}
-		if create.flagIsDemo {
+	}
+	create.branch = answers.Branch
+	if len(create.branch) == 0 {
+		create.branch = create.template.Branch
+	}
+	if len(create.branch) == 0 && create.template.Builtin() {
+		create.branch = consts.BranchProject
+		if answers.Demo != nil && *answers.Demo {
 			create.branch = consts.BranchDemo
 		}
 	}
+	create.module = answers.Module
+	if len(create.module) == 0 {
+		create.module = create.projectName
+	}
+	create.force = answers.Force
+	create.remote = answers.Remote
+	create.push = answers.Push
+	create.initialCommit = answers.InitialCommit || answers.Push
+	// 提交前确认已配置提交者, 避免创建到最后一步才失败
+	if create.initialCommit {
+		if err = checkGitIdentity(); err != nil {
+			create.stopped(err)
+			return
+		}
+	}
+	// 中断时删除未完成的临时目录
+	ctx, stop := signal.NotifyContext(create.ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	create.ctx = ctx
 	go func() {
 		create.done <- create.pullRepo()
 	}()
 	select {
 	case <-create.ctx.Done():
+		// 等待正在执行的命令被终止后再删除临时目录
+		select {
+		case <-create.done:
+		case <-time.After(stageCleanupGrace):
+		}
+		stageDirs, _ := filepath.Glob(filepath.Join(create.projectPath, "."+create.projectName+".aurora-*"))
+		for _, dir := range stageDirs {
+			_ = os.RemoveAll(dir)
+		}
 		if errors.Is(create.ctx.Err(), context.DeadlineExceeded) {
 			fmt.Fprint(os.Stderr, "\033[31mERROR: project creation timed out\033[m\n")
+			os.Exit(1)
 			return
 		}
 		fmt.Fprintf(os.Stderr, "\033[31mERROR: failed to create project(%s)\033[m\n", create.ctx.Err().Error())
+		os.Exit(1)
 	case err = <-create.done:
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "\033[31mERROR: Failed to create project(%s)\033[m\n", err.Error())
+			os.Exit(1)
 		}
 	}
 	return
 }
 
+// ask 提示用户输入, --yes 时直接使用默认值; 标准输入不是终端时返回错误而不是阻塞
+func (create *createCmd) ask(answer string, prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	if create.noInput {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("%w, cannot prompt for %s, pass it on the command line, or use --%s / --%s", errNoTTY, answer, flagYes.name, flagFromFile.name)
+	}
+	return survey.AskOne(prompt, response, opts...)
+}
+
+func (create *createCmd) stopped(err error) {
+	if errors.Is(err, errNoTTY) {
+		fmt.Printf("🚫 %v\n", err)
+	} else {
+		fmt.Printf("🚧 Stopped...something went wrong [%v]\n", err)
+	}
+	os.Exit(1)
+}
+
 func (create *createCmd) pullRepo() (err error) {
-	var (
-		override   bool
-		targetPath = filepath.Join(create.projectPath, create.projectName)
-	)
+	targetPath := filepath.Join(create.projectPath, create.projectName)
 
-	// 目标文件夹已存在
+	// 目标文件夹已存在, 确认覆盖后在新项目创建成功时才替换
+	overwrite := false
 	if _, err = os.Stat(targetPath); !os.IsNotExist(err) {
 		err = nil
 		fmt.Printf("🤔 [Target path: %s] already exists！\n", targetPath)
-		prompt := &survey.Confirm{
-			Message: "Whether to overwrite existing directories ?",
-			Default: false,
-			Help:    "WARNING: Selecting overwrite will delete all content under the existing directory",
-		}
-		if e := survey.AskOne(prompt, &override, survey.WithIcons(func(icons *survey.IconSet) {
-			icons.Question.Text = "📥"
-			icons.Question.Format = "blue+b"
-		})); e != nil {
-			return err
+		overwrite = create.force
+		if !overwrite {
+			prompt := &survey.Confirm{
+				Message: "Whether to overwrite existing directories ?",
+				Default: false,
+				Help:    "WARNING: Selecting overwrite will delete all content under the existing directory",
+			}
+			if e := create.ask("--"+flagForce.name, prompt, &overwrite, survey.WithIcons(func(icons *survey.IconSet) {
+				icons.Question.Text = "📥"
+				icons.Question.Format = "blue+b"
+			})); e != nil {
+				return e
+			}
 		}
-		if !override {
-			return errors.New(fmt.Sprintf("🚫 Failed to create project, target folder already exists..."))
+		if !overwrite {
+			return errors.New(fmt.Sprintf("🚫 Failed to create project, target folder already exists, use --%s to overwrite it...", flagForce.name))
 		}
-		// 清空
-		_ = os.RemoveAll(targetPath)
 	}
-	if err := os.MkdirAll(targetPath, fs.ModePerm); err != nil {
+	if err = os.MkdirAll(create.projectPath, fs.ModePerm); err != nil {
+		return err
+	}
+	// 在目标目录旁的临时目录中创建项目, 保证最后可以直接重命名
+	stageDir, err := os.MkdirTemp(create.projectPath, "."+create.projectName+".aurora-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(stageDir)
+		}
+	}()
+	cacheDir, err := templates.DefaultCacheDir()
+	if err != nil {
 		return err
 	}
-	fmt.Printf("\n\n🚀 Creating project: [%s] [From %s To: %s], Pulling GIT branch[%s], please wait...\n", color.GreenString(create.projectName), color.BlueString(consts.GoFrameRepoUrl), color.BlueString(create.projectPath), color.BlueString(create.branch))
-	if err = create.cloneRepoWithGit(targetPath); err != nil {
-		_ = os.RemoveAll(targetPath)
-		return errors.New(fmt.Sprintf("🚫 Failed to pull the remote git repostory, unable to create the project... (err: %v)", err))
+	kind := templates.KindOf(create.template.Source)
+	if kind == templates.KindGit {
+		fmt.Printf("\n\n🚀 Creating project: [%s] [From %s To: %s], Pulling GIT branch[%s], please wait...\n", color.GreenString(create.projectName), color.BlueString(create.template.Source), color.BlueString(create.projectPath), color.BlueString(lo.Ternary(len(create.branch) > 0, create.branch, "HEAD")))
+	} else {
+		fmt.Printf("\n\n🚀 Creating project: [%s] [From %s %s To: %s], please wait...\n", color.GreenString(create.projectName), kind, color.BlueString(create.template.Source), color.BlueString(create.projectPath))
+	}
+	result, err := templates.NewCache(cacheDir).Fetch(create.template.Source, create.branch, stageDir, create.offline)
+	if err != nil {
+		return errors.New(fmt.Sprintf("🚫 Failed to fetch the template [%s], unable to create the project... (err: %v)", create.template.Name, err))
+	}
+	if result.Stale != nil {
+		fmt.Printf("⚠️ Failed to refresh the template cache, the cached template is used...[%v]\n", result.Stale)
+	}
+	if create.commit = result.Commit; len(create.commit) > 0 {
+		fmt.Printf("📌 Template [%s] at commit [%s]\n", color.GreenString(create.template.Name), color.GreenString(create.commit))
+	}
+	if err = create.applyTemplate(stageDir); err != nil {
+		return errors.New(fmt.Sprintf("🚫 Failed to apply the template [%s], unable to create the project... (err: %v)", create.template.Name, err))
+	}
+	// 写入标准的 .gitignore, 保留模板中已有的规则
+	if err = writeGitignore(stageDir); err != nil {
+		return errors.New(fmt.Sprintf("🚫 Failed to write '.gitignore', unable to create the project... (err: %v)", err))
+	}
+	// 记录模板及其提交, 用于之后升级项目
+	if err = manifest.SetTemplate(stageDir, manifest.Template{
+		Name:        create.template.Name,
+		Source:      create.template.Source,
+		Branch:      create.branch,
+		Commit:      create.commit,
+		ProjectName: create.projectName,
+		Variables:   create.variables,
+	}); err != nil {
+		return errors.New(fmt.Sprintf("🚫 Failed to write '%s', unable to create the project... (err: %v)", manifest.FileName, err))
 	}
 	fmt.Printf("\n⚙️ Successfully pulled project, initializing GIT repository and branch...\n")
-	if err = create.processLocalRepo(targetPath); err != nil {
-		_ = os.RemoveAll(targetPath)
+	if err = create.processLocalRepo(stageDir); err != nil {
 		return errors.New(fmt.Sprintf("🚫 Failed to initialize GIT repository, unable to create project... (err: %v)", err))
 	}
 	fmt.Printf("\n⚙️ Initializing GIT repository and branch succeeded ! initializing go.mod file...\n")
-	if err = create.processGoMod(); err != nil {
-		_ = os.RemoveAll(targetPath)
+	if err = create.processGoMod(stageDir); err != nil {
 		return errors.New(fmt.Sprintf("🚫 There was an error initializing the go.mod file and the project could not be created... (err: %v)", err))
 	}
+	if err = create.confirmHooks(); err != nil {
+		return errors.New(fmt.Sprintf("🚫 The hooks of the template are not confirmed, unable to create the project... (err: %v)", err))
+	}
+	if err = create.runHooks(stageDir); err != nil {
+		return errors.New(fmt.Sprintf("🚫 A post-create hook failed, unable to create the project... (err: %v)", err))
+	}
+	if create.initialCommit {
+		if err = runLogged(create.ctx, stageDir, "git", "add", "-A"); err == nil {
+			err = runLogged(create.ctx, stageDir, "git", "commit", "--quiet", "-m", "Initial commit")
+		}
+		if err != nil {
+			return errors.New(fmt.Sprintf("🚫 Failed to create the initial commit, unable to create the project... (err: %v)", err))
+		}
+		fmt.Printf("✅️ Created the initial commit\n")
+	}
+	if err = swapDir(stageDir, targetPath, overwrite); err != nil {
+		return errors.New(fmt.Sprintf("🚫 Failed to move the project to [%s]... (err: %v)", targetPath, err))
+	}
+	// 项目已创建, 推送失败时只提示
+	pushed := false
+	if create.push {
+		fmt.Printf("\n📤 Pushing branch [%s] to [%s]...\n", color.GreenString(consts.BranchMain), color.BlueString(create.remote))
+		if e := runLogged(create.ctx, targetPath, "git", "push", "--quiet", "-u", "origin", consts.BranchMain); e != nil {
+			fmt.Printf("⚠️ Failed to push to the remote repository, push it later with %s...[%v]\n", color.GreenString("git push -u origin main"), e)
+		} else {
+			pushed = true
+		}
+	}
 	fmt.Printf("\n +++++++++ ️🎉🎊 Project [%s] created successfully...！🍺🍺🍺 +++++++++\n", color.GreenString(create.projectName))
-	fmt.Printf(" 📡 Current local GIT branch: [%s], You can run the command: %s to associate a remote repository...\n", color.GreenString("main"), color.GreenString("git remote add origin <YourGitRepositoryUrl.git>"))
-	fmt.Printf(" 📡 You can run the command: %s, to push your local GIT branch to the remote repository...\n", color.GreenString("git push -u origin main"))
+	if len(create.remote) == 0 {
+		fmt.Printf(" 📡 Current local GIT branch: [%s], You can run the command: %s to associate a remote repository...\n", color.GreenString("main"), color.GreenString("git remote add origin <YourGitRepositoryUrl.git>"))
+	}
+	if !pushed {
+		fmt.Printf(" 📡 You can run the command: %s, to push your local GIT branch to the remote repository...\n", color.GreenString("git push -u origin main"))
+	}
 	fmt.Printf(" 🍻 All processes are successful! Enjoy the fun of coding...🥳\n")
 
 	return nil
 }
 
-func (create *createCmd) cloneRepoWithGit(targetPath string) (err error) {
-	cmd := exec.Command(
-		"git",
-		"clone",
-		consts.GoFrameRepoUrl,
-		targetPath,
-		"-b", create.branch,
-		"--depth", "1",
-		"--single-branch",
-		"--no-tags",
-		"--verbose",
-	)
+// swapDir 将临时目录移动到目标目录, overwrite 为 true 时替换已存在的目标目录, 失败时恢复原目录
+func swapDir(stageDir, targetPath string, overwrite bool) error {
+	if !overwrite {
+		return os.Rename(stageDir, targetPath)
+	}
+	// 使用新的临时目录名, 避免与之前残留的备份冲突
+	backup, err := os.MkdirTemp(filepath.Dir(targetPath), "."+filepath.Base(targetPath)+".backup-")
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(backup); err != nil {
+		return err
+	}
+	if err = os.Rename(targetPath, backup); err != nil {
+		return err
+	}
+	if err = os.Rename(stageDir, targetPath); err != nil {
+		_ = os.Rename(backup, targetPath)
+		return err
+	}
+	return os.RemoveAll(backup)
+}
+
+// confirmHooks 列出模板声明的钩子, 第三方模板的钩子需要确认后才执行, 不执行时清空钩子
+func (create *createCmd) confirmHooks() error {
+	if len(create.hooks) == 0 || create.template.Builtin() {
+		return nil
+	}
+	fmt.Printf("\n🪝 The template [%s] declares %d post-create hook(s):\n", create.template.Name, len(create.hooks))
+	for _, hook := range create.hooks {
+		fmt.Printf("    %s: %s\n", hook.Name, color.YellowString(strings.Join(hook.Run, " ")))
+	}
+	if create.yes {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("%w, cannot confirm the hooks of the template, review them and use --%s (or --%s) to run them", errNoTTY, flagYes.name, flagNoInput.name)
+	}
+	confirmed := false
+	prompt := &survey.Confirm{
+		Message: "Run the hooks in the project directory ?",
+		Help:    "The hooks are commands declared by a third-party template",
+		Default: false,
+	}
+	if err := survey.AskOne(prompt, &confirmed, survey.WithIcons(func(icons *survey.IconSet) {
+		icons.Question.Text = "⚠️"
+		icons.Question.Format = "yellow+b"
+	})); err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("⚠️ The hooks are skipped, run them in the project directory manually if needed...")
+		create.hooks = nil
+	}
+	return nil
+}
+
+// runHooks 按顺序执行模板声明的钩子, 失败时打印钩子的输出
+func (create *createCmd) runHooks(dir string) error {
+	if len(create.hooks) == 0 {
+		return nil
+	}
+	fmt.Printf("\n🪝 Running %d post-create hook(s)...\n", len(create.hooks))
+	for _, hook := range create.hooks {
+		if err := runLogged(create.ctx, dir, hook.Run[0], hook.Run[1:]...); err != nil {
+			if hook.Optional {
+				fmt.Printf("⚠️ Hook [%s] failed and is skipped...[%v]\n", hook.Name, err)
+				continue
+			}
+			return fmt.Errorf("%s: %w", hook.Name, err)
+		}
+		fmt.Printf("✅️ Hook [%s]\n", color.BlueString(hook.Name))
+	}
+	return nil
+}
+
+// runLogged 执行命令并捕获输出, 失败时打印输出, 成功时不打印; ctx 结束时终止命令
+func runLogged(ctx context.Context, dir, name string, args ...string) error {
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
 	if err := cmd.Run(); err != nil {
+		command := strings.Join(append([]string{name}, args...), " ")
+		if output.Len() == 0 {
+			fmt.Printf("🚫 [%s] failed without output\n", command)
+			return err
+		}
+		fmt.Printf("🚫 [%s] failed, output:\n", command)
+		for _, line := range strings.Split(strings.TrimRight(output.String(), "\n"), "\n") {
+			fmt.Printf("    %s\n", color.HiBlackString(line))
+		}
 		return err
 	}
 	return nil
 }
 
+// applyTemplate 根据模板中的 aurora-template.yaml 询问变量, 删除条件不满足的文件并渲染模板文件
+func (create *createCmd) applyTemplate(targetPath string) error {
+	tplManifest, err := templates.LoadManifest(targetPath)
+	if err != nil || tplManifest == nil {
+		return err
+	}
+	fmt.Printf("\n🧩 The template declares %d variable(s) in '%s'...\n", len(tplManifest.Variables), templates.ManifestFile)
+	values := map[string]interface{}{
+		"ProjectName": create.projectName,
+		"Module":      create.module,
+	}
+	declared := make(map[string]bool, len(tplManifest.Variables))
+	create.variables = make(map[string]string, len(tplManifest.Variables))
+	for _, v := range tplManifest.Variables {
+		if values[v.Name], err = create.askVariable(v, values); err != nil {
+			return err
+		}
+		declared[v.Name] = true
+		create.variables[v.Name] = fmt.Sprint(values[v.Name])
+	}
+	for name := range create.answers.Variables {
+		if !declared[name] {
+			fmt.Printf("⚠️ The variable [%s] is not declared by the template and is ignored...\n", name)
+		}
+	}
+	if create.hooks, err = tplManifest.ResolveHooks(values); err != nil {
+		return err
+	}
+	removed, rendered, err := tplManifest.Apply(targetPath, values)
+	if err != nil {
+		return err
+	}
+	for _, file := range removed {
+		fmt.Printf("✅️ Excluded: %s\n", color.HiBlackString(file))
+	}
+	for _, file := range rendered {
+		fmt.Printf("✅️ Rendered: %s\n", color.BlueString(file))
+	}
+	return nil
+}
+
+// askVariable 询问模板变量, 已通过 --var 或答案文件指定的变量不再询问
+func (create *createCmd) askVariable(v templates.Variable, values map[string]interface{}) (interface{}, error) {
+	if answer, ok := create.answers.Variables[v.Name]; ok {
+		value, err := v.Parse(answer)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", v.Name, err)
+		}
+		return value, nil
+	}
+	defaultValue, err := v.DefaultValue(values)
+	if err != nil {
+		return nil, fmt.Errorf("the default value of variable %s: %w", v.Name, err)
+	}
+	var (
+		answer  = defaultValue
+		message = lo.Ternary(len(v.Prompt) > 0, v.Prompt, v.Name)
+		icons   = survey.WithIcons(func(icons *survey.IconSet) {
+			icons.Question.Text = "🧩"
+			icons.Question.Format = "blue+b"
+		})
+	)
+	switch v.Type {
+	case templates.VarBool:
+		confirmed, _ := strconv.ParseBool(defaultValue)
+		prompt := &survey.Confirm{Message: message, Help: v.Help, Default: confirmed}
+		if err = create.ask("--"+flagVar.name+" "+v.Name, prompt, &confirmed, icons); err != nil {
+			return nil, err
+		}
+		return confirmed, nil
+	case templates.VarSelect:
+		prompt := &survey.Select{Message: message, Help: v.Help, Options: v.Options}
+		if lo.Contains(v.Options, defaultValue) {
+			prompt.Default = defaultValue
+		}
+		err = create.ask("--"+flagVar.name+" "+v.Name, prompt, &answer, icons)
+	default:
+		prompt := &survey.Input{Message: message, Help: v.Help, Default: defaultValue}
+		err = create.ask("--"+flagVar.name+" "+v.Name, prompt, &answer, icons, survey.WithValidator(func(ans interface{}) error {
+			_, err := v.Parse(fmt.Sprint(ans))
+			return err
+		}))
+	}
+	if err != nil {
+		return nil, err
+	}
+	value, err := v.Parse(answer)
+	if err != nil {
+		return nil, fmt.Errorf("variable %s: %w, set it with --%s %s=<value>", v.Name, err, flagVar.name, v.Name)
+	}
+	return value, nil
+}
+
 func (create *createCmd) processLocalRepo(targetPath string) (err error) {
 	var repo *git.Repository
-	if repo, err = git.PlainOpen(targetPath); err != nil {
+	// 丢弃模板的GIT历史
+	if create.initialCommit {
+		fmt.Printf("✅️ Discard the GIT history of the template...\n")
+		if err = os.RemoveAll(filepath.Join(targetPath, ".git")); err != nil {
+			return err
+		}
+	}
+	if repo, err = git.PlainOpen(targetPath); errors.Is(err, git.ErrRepositoryNotExists) {
+		// 本地目录和压缩包创建的项目初始化新的GIT仓库
+		fmt.Printf("✅ Initialize the local GIT repository...\n")
+		if repo, err = git.PlainInit(targetPath, false); err != nil {
+			return err
+		}
+		if err = repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(consts.BranchMain))); err != nil {
+			return err
+		}
+		return create.addRemote(repo)
+	}
+	if err != nil {
 		return err
 	}
 	fmt.Printf("✅️ Disassociate from remote GIT template repository...\n")
-	if err = repo.DeleteRemote("origin"); err != nil {
+	if err = repo.DeleteRemote("origin"); err != nil && !errors.Is(err, git.ErrRemoteNotFound) {
+		return err
+	}
+	if err = create.addRemote(repo); err != nil {
 		return err
 	}
 
@@ -282,35 +735,85 @@ func (create *createCmd) processLocalRepo(targetPath string) (err error) {
 	return nil
 }
 
-func (create *createCmd) processGoMod() (err error) {
-	var cmd = new(exec.Cmd)
-	// 设置包名为项目名
-	cmd = exec.Command(
-		"go",
-		"mod",
-		"edit",
-		"-module",
-		fmt.Sprintf("%s", create.projectName),
-	)
-	cmd.Dir = fmt.Sprintf("%s/%s", create.projectPath, create.projectName)
-	if err := cmd.Run(); err != nil {
+// addRemote 添加 --remote 指定的远程仓库 origin
+func (create *createCmd) addRemote(repo *git.Repository) error {
+	if len(create.remote) == 0 {
+		return nil
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{create.remote}}); err != nil {
 		return err
 	}
-	fmt.Printf("✅️ Set the module name of 'go.mod' to [%s]\n", color.BlueString(create.projectName))
+	fmt.Printf("✅️ Add the remote GIT repository [origin: %s]\n", color.BlueString(create.remote))
+	return nil
+}
 
-	// 查找并修改引用
-	if err = replaceImport(create.projectName, filepath.Join(create.projectPath, create.projectName)); err != nil {
+// writeGitignore 写入标准的 .gitignore, 模板中已有 .gitignore 时只追加缺少的规则
+func writeGitignore(dir string) error {
+	standard, err := templatesFS.ReadFile("templates/create/gitignore")
+	if err != nil {
 		return err
 	}
+	path := filepath.Join(dir, ".gitignore")
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return os.WriteFile(path, standard, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(string(content), "\n") {
+		existing[strings.TrimSpace(line)] = true
+	}
+	var missing []string
+	for _, line := range strings.Split(string(standard), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") || existing[line] {
+			continue
+		}
+		// bin/ 等价于 /bin/、bin
+		if line == "bin/" && (existing["/bin/"] || existing["bin"] || existing["/bin"]) {
+			continue
+		}
+		missing = append(missing, line)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	content = append(content, []byte("\n# aurora\n"+strings.Join(missing, "\n")+"\n")...)
+	return os.WriteFile(path, content, 0644)
+}
+
+// checkGitIdentity 检查git是否配置了 user.name 和 user.email
+func checkGitIdentity() error {
+	for _, key := range []string{"user.name", "user.email"} {
+		output, err := exec.Command("git", "config", "--get", key).Output()
+		if err != nil || len(strings.TrimSpace(string(output))) == 0 {
+			return fmt.Errorf("the git %s is not configured, set it with 'git config --global %s <value>' before using --%s", key, key, flagInitCommit.name)
+		}
+	}
+	return nil
+}
+
+func (create *createCmd) processGoMod(projectDir string) (err error) {
+	// 模板的包名, 读取失败时使用 prepare2go 的包名
+	templateModule, err := helpers.ModulePath(filepath.Join(projectDir, "go.mod"))
+	if err != nil {
+		templateModule = consts.GoFrameModule
+	}
+	// 设置包名并修改所有引用
+	if templateModule != create.module {
+		if _, err = renameModule(projectDir, templateModule, create.module, false); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("✅️ Set the module name of 'go.mod' to [%s]\n", color.BlueString(create.module))
 
 	// tidy
-	cmd = exec.Command(
-		"go",
-		"mod",
-		"tidy",
-	)
-	cmd.Dir = fmt.Sprintf("%s/%s", create.projectPath, create.projectName)
-	if err := cmd.Run(); err != nil {
+	if err = runLogged(create.ctx, projectDir, "go", "mod", "tidy"); err != nil {
 		return err
 	}
 	fmt.Printf("✅️ go mod tidy...\n")
@@ -320,6 +823,69 @@ func (create *createCmd) processGoMod() (err error) {
 func addCreateRuntimeFlag(cmd *cobra.Command, persistent bool) {
 	getFlags(cmd, persistent).StringP(flagProjectPath.name, flagProjectPath.shortName, flagProjectPath.defaultValue.(string), flagProjectPath.usage)
 	getFlags(cmd, persistent).BoolP(flagIsDemo.name, flagIsDemo.shortName, flagIsDemo.defaultValue.(bool), flagIsDemo.usage)
+	getFlags(cmd, persistent).BoolP(flagYes.name, flagYes.shortName, flagYes.defaultValue.(bool), flagYes.usage)
+	getFlags(cmd, persistent).Bool(flagNoInput.name, flagNoInput.defaultValue.(bool), flagNoInput.usage)
+	getFlags(cmd, persistent).StringP(flagModule.name, flagModule.shortName, flagModule.defaultValue.(string), flagModule.usage)
+	getFlags(cmd, persistent).StringP(flagBranch.name, flagBranch.shortName, flagBranch.defaultValue.(string), flagBranch.usage)
+	getFlags(cmd, persistent).BoolP(flagForce.name, flagForce.shortName, flagForce.defaultValue.(bool), flagForce.usage)
+	getFlags(cmd, persistent).String(flagFromFile.name, flagFromFile.defaultValue.(string), flagFromFile.usage)
+	getFlags(cmd, persistent).Bool(flagOffline.name, flagOffline.defaultValue.(bool), flagOffline.usage)
+	getFlags(cmd, persistent).StringArray(flagVar.name, flagVar.defaultValue.([]string), flagVar.usage)
+	getFlags(cmd, persistent).Bool(flagInitCommit.name, flagInitCommit.defaultValue.(bool), flagInitCommit.usage)
+	getFlags(cmd, persistent).String(flagRemote.name, flagRemote.defaultValue.(string), flagRemote.usage)
+	getFlags(cmd, persistent).Bool(flagPush.name, flagPush.defaultValue.(bool), flagPush.usage)
+	getFlags(cmd, persistent).StringP(flagTemplate.name, flagTemplate.shortName, flagTemplate.defaultValue.(string), flagTemplate.usage)
+}
+
+func getNoInput(cmd *cobra.Command) bool {
+	var (
+		yes, noInput bool
+		err          error
+	)
+	if yes, err = cmd.Flags().GetBool(flagYes.name); err != nil {
+		panic(err)
+	}
+	if noInput, err = cmd.Flags().GetBool(flagNoInput.name); err != nil {
+		panic(err)
+	}
+	return yes || noInput
+}
+
+func getOffline(cmd *cobra.Command) bool {
+	var (
+		offline bool
+		err     error
+	)
+	if offline, err = cmd.Flags().GetBool(flagOffline.name); err != nil {
+		panic(err)
+	}
+	return offline
+}
+
+func getCreateForce(cmd *cobra.Command) bool {
+	var (
+		force bool
+		err   error
+	)
+	if force, err = cmd.Flags().GetBool(flagForce.name); err != nil {
+		panic(err)
+	}
+	return force
+}
+
+// 读取答案文件, 不允许未知的字段
+func readCreateAnswers(path string) (answers createAnswers, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return answers, err
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&answers); err != nil && !errors.Is(err, io.EOF) {
+		return answers, err
+	}
+	return answers, nil
 }
 
 func getProjectPath(cmd *cobra.Command) string {
@@ -337,68 +903,31 @@ func getIsDemo(cmd *cobra.Command) bool {
 	return isDemo
 }
 
+// 解析项目名和项目所在的目录, 项目名可以是相对于 workingDir 的路径或绝对路径, 支持 "~"
 func parseProjectParams(projectName string, workingDir string) (projectNameResult, workingDirResult string) {
-	_projectDir := projectName
-	_workingDir := workingDir
-	if strings.HasPrefix(projectName, "~") {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			// cannot get user home return fallback place dir
-			return _projectDir, _workingDir
-		}
-		_projectDir = filepath.Join(homeDir, projectName[2:])
+	var (
+		projectDir = expandHome(projectName)
+		baseDir    = expandHome(workingDir)
+	)
+	if !filepath.IsAbs(projectDir) {
+		projectDir = filepath.Join(baseDir, projectDir)
 	}
-	if !filepath.IsAbs(workingDir) {
-		absPath, err := filepath.Abs(workingDir)
-		if err != nil {
-			return _projectDir, _workingDir
-		}
-		_projectDir = filepath.Join(absPath, _projectDir)
+	if absPath, err := filepath.Abs(projectDir); err == nil {
+		projectDir = absPath
 	}
 
-	return filepath.Base(_projectDir), filepath.Dir(_projectDir)
+	return filepath.Base(projectDir), filepath.Dir(projectDir)
 }
 
-func replaceImport(moduleName, workdir string) (err error) {
-	// 替换main文件
-	mainPath := filepath.Join(workdir, "main.go")
-	content, err := ioutil.ReadFile(mainPath)
-	if err != nil {
-		return err
+// expandHome 将以 "~/" 开头的路径展开为用户目录下的路径
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
 	}
-	newContent := strings.ReplaceAll(string(content), consts.GoFrameModule, moduleName)
-	err = ioutil.WriteFile(mainPath, []byte(newContent), 0644)
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return err
+		// cannot get user home return fallback place dir
+		return path
 	}
-	fmt.Printf("💡 Replace references in file: %s with: %s\n", mainPath, moduleName)
-
-	// 替换文件夹中的文件
-	for _, dir := range replaceDirs {
-		err = filepath.Walk(filepath.Join(workdir, dir), func(path string, info fs.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if !info.IsDir() {
-				// 读取文件内容
-				content, err := ioutil.ReadFile(path)
-				if err != nil {
-					return err
-				}
-
-				// 查找并替换字符串
-				newContent := strings.ReplaceAll(string(content), consts.GoFrameModule, moduleName)
-
-				// 将替换后的内容写回文件
-				err = ioutil.WriteFile(path, []byte(newContent), info.Mode())
-				if err != nil {
-					return err
-				}
-				fmt.Printf("💡 Replace references in file: %s with: %s\n", path, color.GreenString(fmt.Sprintf("module: %s", moduleName)))
-			}
-			return nil
-		})
-	}
-
-	return err
+	return filepath.Join(homeDir, path[1:])
 }
@@ -3,9 +3,9 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
-	"github.com/stubborn-gaga-0805/aurora/consts"
 	"os"
 	"os/exec"
+	"path/filepath"
 )
 
 type cronCmd struct {
@@ -35,6 +35,9 @@ func newCronCmd() *cronCmd {
 		},
 	}
 	addCrontabRuntimeFlag(c.cmd, true)
+	addEnvFileFlag(c.cmd, true)
+	addForceBuildFlag(c.cmd, true)
+	addProfileFlag(c.cmd, true)
 
 	return c
 }
@@ -48,11 +51,13 @@ func (c *cronCmd) initCrontabRuntime(cmd *cobra.Command) {
 	}
 
 	c.id, _ = os.Hostname()
-	c.env = Env(os.Getenv(consts.OSEnvKey))
-	c.configFilePath = fmt.Sprintf("./configs/config.%s.yaml", c.env)
+	c.resolveEnv(cmd)
+	c.configFilePath = filepath.Join("./configs", c.env.ConfigFile())
 	c.crontabFlags = &crontabFlags{
 		crontabList: getCrontabList(c.cmd),
 	}
+	c.forceBuild = getForceBuild(cmd)
+	c.initProfile(cmd, c.Manifest().Build.RunProfile)
 	return
 }
 
@@ -65,6 +70,7 @@ func (c *cronCmd) run() {
 		goArgs = append(goArgs, "-l")
 	}
 	fd := exec.Command(bin, goArgs...)
+	fd.Env = c.Environ()
 	fd.Stdout = os.Stdout
 	fd.Stderr = os.Stderr
 	if err := fd.Run(); err != nil {
@@ -0,0 +1,417 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/conf"
+	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/config"
+	"github.com/stubborn-gaga-0805/aurora/pkg/manifest"
+	"github.com/stubborn-gaga-0805/aurora/pkg/mysql"
+	"github.com/stubborn-gaga-0805/aurora/pkg/tools"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type doctorCmd struct {
+	*baseCmd
+
+	json      bool
+	checkEnv  Env
+	noDB      bool
+	dbTimeout time.Duration
+	checks    []doctorCheck
+}
+
+// doctorCheck 一项检查的结果
+type doctorCheck struct {
+	Name    string       `json:"name"`
+	Status  doctorStatus `json:"status"`
+	Message string       `json:"message"`
+	// Hint 修复建议
+	Hint string `json:"hint,omitempty"`
+}
+
+type doctorStatus string
+
+const (
+	doctorPass doctorStatus = "pass"
+	doctorWarn doctorStatus = "warn"
+	doctorFail doctorStatus = "fail"
+
+	// protoc 支持的最低主版本
+	minProtocMajor = 3
+)
+
+var (
+	flagDoctorJSON      = flag{"json", "", false, "Print the checks as JSON"}
+	flagDoctorEnv       = flag{"env", "e", "", "The environment whose databases are checked (default: $RUNTIME_ENV or local)"}
+	flagDoctorNoDB      = flag{"no-db", "", false, "Do not connect to the databases"}
+	flagDoctorDBTimeout = flag{"db-timeout", "", 5 * time.Second, "Timeout of each database connection"}
+
+	goVersionPattern     = regexp.MustCompile(`(?:go)?(\d+)\.(\d+)(?:\.(\d+))?`)
+	protocVersionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+)
+
+func newDoctorCmd() *doctorCmd {
+	dc := &doctorCmd{baseCmd: newBaseCmd()}
+	dc.cmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the development environment and the health of the project",
+		Long:  "💡 Check the Go version, the tools, the configs and the databases of the project, eg: aurora doctor --json",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dc.initDoctorRuntime(cmd)
+			dc.run()
+		},
+	}
+	getFlags(dc.cmd, false).Bool(flagDoctorJSON.name, flagDoctorJSON.defaultValue.(bool), flagDoctorJSON.usage)
+	getFlags(dc.cmd, false).StringP(flagDoctorEnv.name, flagDoctorEnv.shortName, flagDoctorEnv.defaultValue.(string), flagDoctorEnv.usage)
+	registerEnvCompletion(dc.cmd, flagDoctorEnv.name)
+	getFlags(dc.cmd, false).Bool(flagDoctorNoDB.name, flagDoctorNoDB.defaultValue.(bool), flagDoctorNoDB.usage)
+	getFlags(dc.cmd, false).Duration(flagDoctorDBTimeout.name, flagDoctorDBTimeout.defaultValue.(time.Duration), flagDoctorDBTimeout.usage)
+
+	return dc
+}
+
+func (dc *doctorCmd) initDoctorRuntime(cmd *cobra.Command) {
+	dc.json, _ = cmd.Flags().GetBool(flagDoctorJSON.name)
+	dc.noDB, _ = cmd.Flags().GetBool(flagDoctorNoDB.name)
+	dc.dbTimeout, _ = cmd.Flags().GetDuration(flagDoctorDBTimeout.name)
+	dc.env = Env(os.Getenv(consts.OSEnvKey))
+	dc.checkEnv = Env(cmd.Flag(flagDoctorEnv.name).Value.String())
+	if len(dc.checkEnv) == 0 {
+		dc.checkEnv = dc.env
+	}
+	if len(dc.checkEnv) == 0 {
+		dc.checkEnv = defaultRuntimeEnv()
+	}
+	return
+}
+
+func (dc *doctorCmd) run() {
+	dc.checkRuntimeEnv()
+	goModExists := dc.checkProjectFiles()
+	if goModExists {
+		dc.checkGoVersion()
+	}
+	dc.checkTools()
+	dc.checkProtoc()
+	if dc.InProjectPath() {
+		dc.checkConfigs()
+	}
+
+	failed := 0
+	for _, check := range dc.checks {
+		if check.Status == doctorFail {
+			failed++
+		}
+	}
+	if dc.json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(dc.checks); err != nil {
+			fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", dc.cmd.Use, err)
+			os.Exit(1)
+			return
+		}
+	} else {
+		dc.printChecks()
+	}
+	if failed > 0 {
+		os.Exit(1)
+		return
+	}
+	return
+}
+
+func (dc *doctorCmd) add(name string, status doctorStatus, message, hint string) {
+	dc.checks = append(dc.checks, doctorCheck{Name: name, Status: status, Message: message, Hint: hint})
+}
+
+// checkRuntimeEnv 检查 RUNTIME_ENV
+func (dc *doctorCmd) checkRuntimeEnv() {
+	const name = consts.OSEnvKey
+	switch {
+	case len(dc.env) == 0:
+		dc.add(name, doctorWarn, "not set, the commands use their default environment", fmt.Sprintf("export %s=%s", consts.OSEnvKey, defaultRuntimeEnv()))
+	case !dc.env.Check():
+		dc.add(name, doctorFail, fmt.Sprintf("%q is not a supported environment", dc.env), fmt.Sprintf("set %s to one of: %s", consts.OSEnvKey, joinEnvs(supportsRuntimeEnvs())))
+	default:
+		dc.add(name, doctorPass, dc.env.ToString(), "")
+	}
+}
+
+// checkProjectFiles 检查 main.go、go.mod, 返回 go.mod 是否存在
+func (dc *doctorCmd) checkProjectFiles() bool {
+	goModExists := false
+	for _, file := range []string{"main.go", "go.mod"} {
+		if _, err := os.Stat(filepath.Join(dc.workingDir, file)); err != nil {
+			dc.add(file, doctorFail, "not found in "+dc.workingDir, "run aurora doctor in the project root directory")
+			continue
+		}
+		goModExists = goModExists || file == "go.mod"
+		dc.add(file, doctorPass, "found", "")
+	}
+	return goModExists
+}
+
+// checkGoVersion 检查本地的go版本是否满足 go.mod 的要求
+func (dc *doctorCmd) checkGoVersion() {
+	const name = "go version"
+	required, err := helpers.GoVersion(filepath.Join(dc.workingDir, "go.mod"))
+	if err != nil {
+		dc.add(name, doctorWarn, err.Error(), "add a go directive to go.mod, eg: go 1.20")
+		return
+	}
+	// 在项目外执行并禁止切换工具链, 否则 go.mod 要求更高的版本时 go 会下载或拒绝执行
+	cmd := exec.Command("go", "env", "GOVERSION")
+	cmd.Dir = os.TempDir()
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	output, err := cmd.Output()
+	if err != nil {
+		dc.add(name, doctorFail, "go is not found on PATH", "install Go from https://go.dev/dl/")
+		return
+	}
+	installed := strings.TrimSpace(string(output))
+	installedVersion, ok := parseVersion(goVersionPattern, installed)
+	if !ok {
+		dc.add(name, doctorWarn, fmt.Sprintf("cannot parse the installed go version %q", installed), "check the output of: go env GOVERSION")
+		return
+	}
+	requiredVersion, ok := parseVersion(goVersionPattern, required)
+	if !ok {
+		dc.add(name, doctorWarn, fmt.Sprintf("cannot parse the go version %q in go.mod", required), "fix the go directive in go.mod, eg: go 1.20")
+		return
+	}
+	if compareVersions(installedVersion, requiredVersion) < 0 {
+		dc.add(name, doctorFail, fmt.Sprintf("%s is older than go %s required by go.mod", installed, required), "upgrade Go to "+required+" or later")
+		return
+	}
+	dc.add(name, doctorPass, fmt.Sprintf("%s (go.mod: %s)", installed, required), "")
+}
+
+// checkTools 检查项目需要的工具, 优先使用 ./bin/tools 中的工具
+func (dc *doctorCmd) checkTools() {
+	list, _, err := projectTools(dc.workingDir, dc.doctorManifest())
+	if err != nil {
+		dc.add("tools", doctorFail, err.Error(), "fix tools.go or declare the tools in "+manifest.FileName)
+		return
+	}
+	for _, tool := range list {
+		name := "tool " + tool.Binary()
+		check, err := tools.CheckTool(dc.workingDir, tool)
+		if err != nil {
+			dc.add(name, doctorWarn, err.Error(), "aurora init --only "+tool.Binary())
+			continue
+		}
+		switch check.Status {
+		case tools.StatusOK, tools.StatusUnpinned:
+			dc.add(name, doctorPass, fmt.Sprintf("%s %s", "./"+tools.Dir, check.Installed), "")
+			continue
+		case tools.StatusDrift:
+			dc.add(name, doctorWarn, fmt.Sprintf("%s is installed, %s is declared", check.Installed, tool.Version), "aurora init --only "+tool.Binary())
+			continue
+		}
+		// 项目中没有安装时使用 PATH 中的工具
+		path, err := exec.LookPath(tool.Binary())
+		if err != nil {
+			dc.add(name, doctorFail, "not found in ./"+tools.Dir+" or on PATH", "aurora init --only "+tool.Binary())
+			continue
+		}
+		dc.add(name, doctorPass, path, "")
+	}
+}
+
+// checkProtoc 检查 protoc, 项目中没有 .proto 文件时只提示
+func (dc *doctorCmd) checkProtoc() {
+	const name = "tool protoc"
+	status := doctorWarn
+	if dc.hasProtoFiles() {
+		status = doctorFail
+	}
+	output, err := exec.Command("protoc", "--version").Output()
+	if err != nil {
+		dc.add(name, status, "not found on PATH", "install protoc from https://github.com/protocolbuffers/protobuf/releases")
+		return
+	}
+	version := strings.TrimSpace(string(output))
+	v, ok := parseVersion(protocVersionPattern, version)
+	if !ok {
+		dc.add(name, doctorWarn, fmt.Sprintf("cannot parse the version %q", version), "")
+		return
+	}
+	if v[0] < minProtocMajor {
+		dc.add(name, status, version+" is too old", fmt.Sprintf("upgrade protoc to %d.x or later", minProtocMajor))
+		return
+	}
+	dc.add(name, doctorPass, version, "")
+}
+
+// checkConfigs 检查所有环境的配置文件 (config.yaml 与 config.<env>.yaml 合并后), 并连接 --env 环境中的数据库
+func (dc *doctorCmd) checkConfigs() {
+	dir := filepath.Join(dc.workingDir, "configs")
+	for _, env := range supportsRuntimeEnvs() {
+		var (
+			rel     = filepath.Join("configs", env.ConfigFile())
+			name    = "config " + env.ToString()
+			files   = conf.ConfigFiles(dir, env.ConfigFile())
+			configs *conf.App
+		)
+		if len(files) == 0 {
+			status := doctorWarn
+			if env == dc.checkEnv {
+				status = doctorFail
+			}
+			dc.add(name, status, rel+" not found", "create "+rel)
+			continue
+		}
+		issues, err := config.ValidateFiles(files...)
+		if err != nil {
+			dc.add(name, doctorFail, err.Error(), "fix "+rel)
+			continue
+		}
+		if len(issues) > 0 {
+			issues[0].File = dc.relFiles([]string{issues[0].File})[0]
+			dc.add(name, doctorFail, fmt.Sprintf("%s (%d issues)", issues[0], len(issues)), "run: aurora config validate -e "+env.ToString())
+			continue
+		}
+		layered, err := conf.LoadLayered(conf.LoadOptions{Dir: dir, Env: env.ToString(), EnvFile: env.ConfigFile(), Environ: os.Environ()})
+		if err == nil {
+			err = layered.Unmarshal(&configs)
+		}
+		if err != nil {
+			dc.add(name, doctorFail, err.Error(), "fix "+rel)
+			continue
+		}
+		dc.add(name, doctorPass, strings.Join(dc.relFiles(files), " + "), "")
+		if env == dc.checkEnv && !dc.noDB {
+			dc.checkDatabases(env, layered)
+		}
+	}
+}
+
+// checkDatabases 连接配置中的数据库
+func (dc *doctorCmd) checkDatabases(env Env, layered *conf.Layered) {
+	file := filepath.Base(layered.Files[len(layered.Files)-1])
+	conns, err := loadDataConnections(layered)
+	if err != nil {
+		dc.add("db "+env.ToString(), doctorFail, err.Error(), "fix the data section of "+file)
+		return
+	}
+	names := make([]string, 0, len(conns))
+	for name := range conns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var (
+			conn        = conns[name]
+			check       = fmt.Sprintf("db %s.%s", env, name)
+			ctx, cancel = context.WithTimeout(dc.ctx, dc.dbTimeout)
+		)
+		if conn.MaxDialTimeout <= 0 || conn.MaxDialTimeout > dc.dbTimeout {
+			conn.MaxDialTimeout = dc.dbTimeout
+		}
+		gdb, err := mysql.New(ctx, conn)
+		cancel()
+		if err != nil {
+			dc.add(check, doctorFail, fmt.Sprintf("%s/%s: %v", conn.Addr, conn.Database, err), fmt.Sprintf("check data.%s in %s, or skip it with --%s", name, file, flagDoctorNoDB.name))
+			continue
+		}
+		if sqlDB, err := gdb.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		dc.add(check, doctorPass, fmt.Sprintf("%s/%s", conn.Addr, conn.Database), "")
+	}
+}
+
+// doctorManifest 项目配置, 解析失败时记录检查结果并使用空配置
+func (dc *doctorCmd) doctorManifest() *manifest.Manifest {
+	if dc.manifestErr != nil {
+		dc.add(manifest.FileName, doctorFail, dc.manifestErr.Error(), "fix "+manifest.FileName)
+		return new(manifest.Manifest)
+	}
+	return dc.manifest
+}
+
+func (dc *doctorCmd) hasProtoFiles() bool {
+	found := false
+	_ = filepath.Walk(dc.workingDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || found {
+			return filepath.SkipDir
+		}
+		if info.IsDir() && path != dc.workingDir && (strings.HasPrefix(info.Name(), ".") || info.Name() == "vendor" || info.Name() == "bin") {
+			return filepath.SkipDir
+		}
+		found = strings.HasSuffix(info.Name(), ".proto")
+		return nil
+	})
+	return found
+}
+
+func (dc *doctorCmd) printChecks() {
+	for _, check := range dc.checks {
+		var icon string
+		switch check.Status {
+		case doctorPass:
+			icon = "✅"
+		case doctorWarn:
+			icon = "⚠️"
+		default:
+			icon = "🚫"
+		}
+		fmt.Printf("%s %-24s %s\n", icon, check.Name, check.Message)
+		if len(check.Hint) > 0 && check.Status != doctorPass {
+			fmt.Printf("   💡 %s\n", color.HiBlackString(check.Hint))
+		}
+	}
+	var counts = map[doctorStatus]int{}
+	for _, check := range dc.checks {
+		counts[check.Status]++
+	}
+	fmt.Printf("\n%s passed, %s warnings, %s failed\n", color.GreenString("%d", counts[doctorPass]), color.YellowString("%d", counts[doctorWarn]), color.RedString("%d", counts[doctorFail]))
+}
+
+func joinEnvs(envs []Env) string {
+	names := make([]string, 0, len(envs))
+	for _, env := range envs {
+		names = append(names, env.ToString())
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseVersion 解析版本号中的数字, 如: go1.20.3 -> [1 20 3], 没有匹配时返回 false
+func parseVersion(pattern *regexp.Regexp, version string) ([3]int, bool) {
+	var parts [3]int
+	match := pattern.FindStringSubmatch(version)
+	if match == nil {
+		return parts, false
+	}
+	for i := 1; i < len(match) && i <= 3; i++ {
+		parts[i-1], _ = strconv.Atoi(match[i])
+	}
+	return parts, true
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
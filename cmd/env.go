@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/stubborn-gaga-0805/aurora/pkg/dotenv"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type envCmd struct {
+	*baseCmd
+}
+
+type envPrintCmd struct {
+	*baseCmd
+}
+
+var (
+	flagEnvFile    = flag{"env-file", "", []string{}, "Env files to load, later files override earlier ones (default: .env and .env.<env>)"}
+	flagPrintEnv   = flag{"env", "e", "", "Set the operating environment of the application (default: $RUNTIME_ENV)"}
+	secretKeywords = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIAL", "PRIVATE"}
+)
+
+func newEnvCmd() *envCmd {
+	ec := &envCmd{newBaseCmd()}
+	ec.cmd = &cobra.Command{
+		Use:   "env",
+		Short: "Environment variables related commands",
+		Long:  "💡 Environment variables related commands, eg: aurora env print -e dev",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Usage(); err != nil {
+				panic(err)
+			}
+		},
+	}
+	ec.addCommands(newEnvPrintCmd())
+
+	return ec
+}
+
+func newEnvPrintCmd() *envPrintCmd {
+	ep := &envPrintCmd{newBaseCmd()}
+	ep.cmd = &cobra.Command{
+		Use:   "print",
+		Short: "Print the merged environment passed to run/job/cron, secrets are masked",
+		Long:  "💡 Print the merged environment passed to run/job/cron, eg: aurora env print -e dev --env-file .env.local",
+		Run: func(cmd *cobra.Command, args []string) {
+			ep.initEnvPrintRuntime(cmd)
+			ep.run()
+		},
+	}
+	getFlags(ep.cmd, false).StringP(flagPrintEnv.name, flagPrintEnv.shortName, flagPrintEnv.defaultValue.(string), flagPrintEnv.usage)
+	registerEnvCompletion(ep.cmd, flagPrintEnv.name)
+	addEnvFileFlag(ep.cmd, false)
+
+	return ep
+}
+
+func (ep *envPrintCmd) initEnvPrintRuntime(cmd *cobra.Command) {
+	ep.env = Env(cmd.Flag(flagPrintEnv.name).Value.String())
+	if len(ep.env) == 0 {
+		ep.env = Env(os.Getenv(consts.OSEnvKey))
+	}
+	ep.initEnvironment(cmd)
+	if len(ep.env) > 0 {
+		ep.environ.Set(consts.OSEnvKey, ep.env.ToString(), dotenv.SourceAurora)
+	}
+	return
+}
+
+func (ep *envPrintCmd) run() {
+	for _, key := range ep.environ.Keys() {
+		value := ep.environ.Get(key)
+		if isSecretKey(key) && len(value) > 0 {
+			value = "******"
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			value = strconv.Quote(value)
+		}
+		source := ep.environ.Source(key)
+		if source == dotenv.SourceProcess {
+			fmt.Printf("%s=%s\n", key, value)
+			continue
+		}
+		fmt.Printf("%s=%s %s\n", color.GreenString(key), value, color.HiBlackString("# %s", source))
+	}
+	return
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, keyword := range secretKeywords {
+		if strings.Contains(key, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func addEnvFileFlag(cmd *cobra.Command, persistent bool) {
+	getFlags(cmd, persistent).StringSlice(flagEnvFile.name, flagEnvFile.defaultValue.([]string), flagEnvFile.usage)
+}
+
+func getEnvFiles(cmd *cobra.Command) []string {
+	var (
+		files []string
+		err   error
+	)
+	if files, err = cmd.Flags().GetStringSlice(flagEnvFile.name); err != nil {
+		panic(err)
+	}
+	return files
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"embed"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/pkg/scaffold"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+type genCmd struct {
+	*baseCmd
+}
+
+var (
+	flagGenEnv    = flag{"env", "e", "prod", "Set the operating environment of the application"}
+	flagGenOutput = flag{"output", "o", ".", "The directory of the generated files"}
+	flagGenForce  = flag{"force", "f", false, "Overwrite existing files that were not generated by aurora"}
+)
+
+func newGenCmd() *genCmd {
+	gen := &genCmd{newBaseCmd()}
+	gen.cmd = &cobra.Command{
+		Use:   "gen",
+		Short: "Generate deployment files for the project",
+		Long:  "💡 Generate deployment files for the project, eg: aurora gen docker -e prod",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Usage(); err != nil {
+				panic(err)
+			}
+		},
+	}
+	gen.addCommands(
+		newGenDockerCmd(),
+		newGenK8sCmd(),
+		newGenSystemdCmd(),
+	)
+
+	return gen
+}
+
+// genFile 生成的文件
+type genFile struct {
+	// path 相对于输出目录的路径
+	path     string
+	template string
+}
+
+// 渲染模板并写入输出目录, 保留用户编辑区域的内容
+func renderGenFiles(outputDir string, files []genFile, data interface{}, force bool) error {
+	for _, file := range files {
+		tpl, err := template.New(filepath.Base(file.template)).Funcs(template.FuncMap{
+			"join":   strings.Join,
+			"quote":  strconv.Quote,
+			"indent": indent,
+		}).ParseFS(templatesFS, file.template)
+		if err != nil {
+			return err
+		}
+		var sb strings.Builder
+		if err = tpl.Execute(&sb, data); err != nil {
+			return err
+		}
+		path := filepath.Join(outputDir, file.path)
+		result, err := scaffold.Write(path, []byte(sb.String()), force)
+		if err != nil {
+			return err
+		}
+		switch result {
+		case scaffold.Unchanged:
+			fmt.Printf("✅ %s %s\n", path, color.HiBlackString(string(result)))
+		default:
+			fmt.Printf("✅ %s %s\n", path, color.GreenString(string(result)))
+		}
+	}
+	return nil
+}
+
+// indent 为每一个非空行添加缩进
+func indent(spaces int, s string) string {
+	var (
+		pad   = strings.Repeat(" ", spaces)
+		lines = strings.Split(strings.TrimRight(s, "\n"), "\n")
+	)
+	for i, line := range lines {
+		if len(line) > 0 {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func addGenRuntimeFlag(cmd *cobra.Command, persistent bool) {
+	getFlags(cmd, persistent).StringP(flagGenEnv.name, flagGenEnv.shortName, flagGenEnv.defaultValue.(string), flagGenEnv.usage)
+	registerEnvCompletion(cmd, flagGenEnv.name)
+	getFlags(cmd, persistent).StringP(flagGenOutput.name, flagGenOutput.shortName, flagGenOutput.defaultValue.(string), flagGenOutput.usage)
+	getFlags(cmd, persistent).BoolP(flagGenForce.name, flagGenForce.shortName, flagGenForce.defaultValue.(bool), flagGenForce.usage)
+}
+
+func getGenEnv(cmd *cobra.Command) Env {
+	return Env(cmd.Flag(flagGenEnv.name).Value.String())
+}
+
+func getGenOutput(cmd *cobra.Command) string {
+	return cmd.Flag(flagGenOutput.name).Value.String()
+}
+
+func getGenForce(cmd *cobra.Command) bool {
+	var (
+		force bool
+		err   error
+	)
+	if force, err = cmd.Flags().GetBool(flagGenForce.name); err != nil {
+		panic(err)
+	}
+	return force
+}
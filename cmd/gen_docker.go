@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/conf"
+	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/kube"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultGoImageVersion = "1.20"
+	mysqlContainerPort    = "3306"
+)
+
+type genDockerCmd struct {
+	*baseCmd
+
+	outputDir string
+	force     bool
+}
+
+// dockerTemplateData Dockerfile 和 docker-compose 模板的数据
+type dockerTemplateData struct {
+	Env string
+	// ConfigFiles 分层合并的配置文件, 合并并移除密码后为 Config 写入 ConfigSource; ConfigFile 为镜像中传递给服务的配置文件
+	ConfigFiles  []string
+	Config       string
+	ConfigSource string
+	ConfigFile   string
+	// Secrets 从配置中移除的密码对应的环境变量, 运行时注入
+	Secrets   []string
+	GoVersion string
+	BuildEnv  []string
+	BuildArgs string
+	CGO       bool
+	MySQL     []mysqlService
+}
+
+// mysqlService 根据数据库连接生成的MySQL容器, 相同地址的连接共用一个容器
+type mysqlService struct {
+	Name        string
+	Addr        string
+	Port        string
+	Database    string
+	Username    string
+	PasswordVar string
+	Conns       []string
+}
+
+var (
+	envVarCleaner = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+	dockerFiles = []genFile{
+		{path: "Dockerfile", template: "templates/docker/Dockerfile.tmpl"},
+		{path: "docker-compose.yaml", template: "templates/docker/docker-compose.yaml.tmpl"},
+		{path: ".dockerignore", template: "templates/docker/dockerignore.tmpl"},
+	}
+)
+
+func newGenDockerCmd() *genDockerCmd {
+	gd := &genDockerCmd{baseCmd: newBaseCmd()}
+	gd.cmd = &cobra.Command{
+		Use:   "docker",
+		Short: "Generate a multi-stage Dockerfile and a docker-compose file with the MySQL dependencies",
+		Long:  "💡 Generate a multi-stage Dockerfile and a docker-compose file, eg: aurora gen docker -e prod --profile release",
+		Run: func(cmd *cobra.Command, args []string) {
+			gd.initGenDockerRuntime(cmd)
+			gd.run()
+		},
+	}
+	addGenRuntimeFlag(gd.cmd, true)
+	addProfileFlag(gd.cmd, true)
+
+	return gd
+}
+
+func (gd *genDockerCmd) initGenDockerRuntime(cmd *cobra.Command) {
+	// 检查是否在项目目录下
+	if !gd.InProjectPath() {
+		fmt.Println("🚫 The 'main.go' file is not found in the current directory, please run it in the project root directory...")
+		os.Exit(1)
+		return
+	}
+	gd.env = getGenEnv(cmd)
+	checkRuntimeEnv(gd.env)
+	gd.outputDir = getGenOutput(cmd)
+	gd.force = getGenForce(cmd)
+	gd.initProfile(cmd, gd.Manifest().Build.BuildProfile())
+	return
+}
+
+func (gd *genDockerCmd) run() {
+	layered, err := conf.LoadLayered(conf.LoadOptions{Dir: filepath.Join(gd.workingDir, "configs"), Env: gd.env.ToString(), EnvFile: gd.env.ConfigFile()})
+	if err != nil {
+		fmt.Printf("🚫 Failed to read the config file...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	conns, err := loadDataConnections(layered)
+	if err != nil {
+		fmt.Printf("🚫 Failed to read the config file...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	configFiles := lo.Map(gd.relFiles(layered.Files), func(file string, _ int) string { return filepath.ToSlash(file) })
+	files := append([]genFile{}, dockerFiles...)
+	goVersion, err := helpers.GoVersion(filepath.Join(gd.workingDir, "go.mod"))
+	if err != nil {
+		goVersion = defaultGoImageVersion
+	}
+	data := dockerTemplateData{
+		Env:         gd.env.ToString(),
+		ConfigFiles: configFiles,
+		ConfigFile:  filepath.ToSlash(filepath.Join("configs", gd.env.ConfigFile())),
+		GoVersion:   goVersion,
+		BuildEnv:    gd.profile.Environ(),
+		BuildArgs:   shellJoin(gd.profile.Args()),
+		CGO:         gd.profile.CGO != nil && *gd.profile.CGO,
+		MySQL:       newMySQLServices(conns),
+	}
+	// 服务只读取一个配置文件, 分层的配置合并并移除密码后写入 <output>/docker/ 再拷贝到镜像中, 密码在运行时通过环境变量注入
+	content, err := layered.YAML()
+	if err != nil {
+		fmt.Printf("🚫 Failed to merge the config files...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	config, secrets, err := kube.SplitSecrets(content)
+	if err != nil {
+		fmt.Printf("🚫 Failed to merge the config files...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	data.Config = strings.TrimRight(string(config), "\n")
+	data.Secrets = lo.Keys(secrets)
+	sort.Strings(data.Secrets)
+	merged := filepath.Join("docker", gd.env.ConfigFile())
+	outputDir := gd.outputDir
+	if !filepath.IsAbs(outputDir) {
+		outputDir = filepath.Join(gd.workingDir, outputDir)
+	}
+	if data.ConfigSource, err = filepath.Rel(gd.workingDir, filepath.Join(outputDir, merged)); err != nil {
+		fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", gd.cmd.Use, err)
+		os.Exit(1)
+		return
+	}
+	data.ConfigSource = filepath.ToSlash(data.ConfigSource)
+	files = append(files, genFile{path: merged, template: "templates/docker/config.yaml.tmpl"})
+	if !data.CGO && !lo.SomeBy(data.BuildEnv, func(kv string) bool { return strings.HasPrefix(kv, "CGO_ENABLED=") }) {
+		data.BuildEnv = append([]string{"CGO_ENABLED=0"}, data.BuildEnv...)
+	}
+	// 环境变量的值可能包含空格或 $, 在 RUN 中需要转义
+	data.BuildEnv = lo.Map(data.BuildEnv, func(kv string, _ int) string { return shellEnv(kv) })
+	fmt.Printf("🐳 Generating docker files for env [%s] with build profile [%s]...\n", color.GreenString(data.Env), color.GreenString(gd.profileName))
+	if err = renderGenFiles(gd.outputDir, files, data, gd.force); err != nil {
+		fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", gd.cmd.Use, err)
+		os.Exit(1)
+		return
+	}
+	if len(data.MySQL) > 0 || len(data.Secrets) > 0 {
+		fmt.Printf("\n💡 Set the following variables in '.env' before running %s:\n", color.GreenString("docker compose up"))
+		for _, svc := range data.MySQL {
+			fmt.Printf("   %s=<password of %s@%s>\n", svc.PasswordVar, svc.Username, svc.Addr)
+		}
+		for _, name := range data.Secrets {
+			fmt.Printf("   %s=<removed from %s>\n", name, data.ConfigSource)
+		}
+	}
+	if len(data.MySQL) > 0 {
+		fmt.Printf("💡 Inside compose the app reaches MySQL by service name, eg: %s\n", color.GreenString("addr: %s:%s", data.MySQL[0].Name, mysqlContainerPort))
+	}
+	return
+}
+
+// 根据数据库连接及其主从配置生成MySQL容器
+func newMySQLServices(conns map[string]conf.DB) []mysqlService {
+	var (
+		names    = lo.Keys(conns)
+		services = make([]mysqlService, 0, len(conns))
+		byAddr   = make(map[string]int)
+	)
+	sort.Strings(names)
+	add := func(conn string, db conf.DB) {
+		if db.Driver != conf.MySQL || len(db.Addr) == 0 {
+			return
+		}
+		if idx, ok := byAddr[db.Addr]; ok {
+			services[idx].Conns = append(services[idx].Conns, conn)
+			return
+		}
+		name := "mysql-" + strings.ToLower(envVarCleaner.ReplaceAllString(conn, "-"))
+		port := mysqlContainerPort
+		if _, p, err := net.SplitHostPort(db.Addr); err == nil {
+			port = p
+		}
+		byAddr[db.Addr] = len(services)
+		services = append(services, mysqlService{
+			Name:        name,
+			Addr:        db.Addr,
+			Port:        port,
+			Database:    db.Database,
+			Username:    db.Username,
+			PasswordVar: strings.ToUpper(envVarCleaner.ReplaceAllString(name, "_")) + "_PASSWORD",
+			Conns:       []string{conn},
+		})
+	}
+	for _, name := range names {
+		db := conns[name]
+		add(name, db)
+		for i, resolver := range db.Resolvers {
+			add(fmt.Sprintf("%s-%s-%d", name, resolver.Type, i), conf.DB{
+				Driver:   db.Driver,
+				Addr:     resolver.Addr,
+				Database: lo.Ternary(len(resolver.Database) > 0, resolver.Database, db.Database),
+				Username: lo.Ternary(len(resolver.Username) > 0, resolver.Username, db.Username),
+			})
+		}
+	}
+	return services
+}
+
+// shellJoin 拼接shell参数, 包含特殊字符的参数使用单引号
+func shellJoin(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellEnv 转义 KEY=VALUE 中的值, 如: GOFLAGS='-mod=mod -tags=a b'
+func shellEnv(kv string) string {
+	key, value, _ := strings.Cut(kv, "=")
+	return key + "=" + shellQuote(value)
+}
+
+func shellQuote(arg string) string {
+	if strings.ContainsAny(arg, " \t\"'$&|;<>()*?`\\") {
+		return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return arg
+}
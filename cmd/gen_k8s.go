@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/conf"
+	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/kube"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type genK8sCmd struct {
+	*baseCmd
+
+	envs      []Env
+	outputDir string
+	force     bool
+	image     string
+	port      int
+	replicas  int
+}
+
+// k8sBaseData base 目录下模板的数据
+type k8sBaseData struct {
+	App      string
+	Image    string
+	Port     int
+	Replicas int
+	Tasks    []k8sTask
+}
+
+type k8sTask struct {
+	Name     string
+	Schedule string
+	Args     []string
+}
+
+// k8sOverlayData overlays/<env> 目录下模板的数据
+type k8sOverlayData struct {
+	App        string
+	Image      string
+	Env        string
+	ConfigFile string
+	Config     string
+	Secrets    map[string]string
+}
+
+var (
+	flagK8sEnvs     = flag{"env", "e", []string{}, "Environments to generate overlays for (default: every env that has a config file)"}
+	flagK8sOutput   = flag{"output", "o", "deploy/k8s", "The directory of the generated files"}
+	flagK8sImage    = flag{"image", "", "", "The container image (default: <app>:latest)"}
+	flagK8sPort     = flag{"port", "", 8080, "The http port of the application"}
+	flagK8sReplicas = flag{"replicas", "", 1, "The replicas of the deployment"}
+
+	dns1123Cleaner = regexp.MustCompile(`[^a-z0-9-]+`)
+
+	k8sBaseFiles = []genFile{
+		{path: "base/deployment.yaml", template: "templates/k8s/deployment.yaml.tmpl"},
+		{path: "base/service.yaml", template: "templates/k8s/service.yaml.tmpl"},
+		{path: "base/kustomization.yaml", template: "templates/k8s/base-kustomization.yaml.tmpl"},
+	}
+	k8sCronJobFile  = genFile{path: "base/cronjob.yaml", template: "templates/k8s/cronjob.yaml.tmpl"}
+	k8sOverlayFiles = []genFile{
+		{path: "kustomization.yaml", template: "templates/k8s/overlay-kustomization.yaml.tmpl"},
+		{path: "configmap.yaml", template: "templates/k8s/configmap.yaml.tmpl"},
+		{path: "secret.env", template: "templates/k8s/secret.env.tmpl"},
+		{path: ".gitignore", template: "templates/k8s/gitignore.tmpl"},
+	}
+)
+
+func newGenK8sCmd() *genK8sCmd {
+	gk := &genK8sCmd{baseCmd: newBaseCmd()}
+	gk.cmd = &cobra.Command{
+		Use:     "k8s",
+		Aliases: []string{"kubernetes"},
+		Short:   "Generate Kubernetes manifests with kustomize overlays per environment",
+		Long:    "💡 Generate Kubernetes manifests with kustomize overlays per environment, eg: aurora gen k8s -e prod --image registry/app:v1.0",
+		Run: func(cmd *cobra.Command, args []string) {
+			gk.initGenK8sRuntime(cmd)
+			gk.run()
+		},
+	}
+	addGenK8sRuntimeFlag(gk.cmd, true)
+
+	return gk
+}
+
+func (gk *genK8sCmd) initGenK8sRuntime(cmd *cobra.Command) {
+	// 检查是否在项目目录下
+	if !gk.InProjectPath() {
+		fmt.Println("🚫 The 'main.go' file is not found in the current directory, please run it in the project root directory...")
+		os.Exit(1)
+		return
+	}
+	gk.outputDir = cmd.Flag(flagK8sOutput.name).Value.String()
+	gk.image = cmd.Flag(flagK8sImage.name).Value.String()
+	gk.force = getGenForce(cmd)
+	gk.port, _ = cmd.Flags().GetInt(flagK8sPort.name)
+	gk.replicas, _ = cmd.Flags().GetInt(flagK8sReplicas.name)
+	envs, err := cmd.Flags().GetStringSlice(flagK8sEnvs.name)
+	if err != nil {
+		panic(err)
+	}
+	for _, env := range envs {
+		checkRuntimeEnv(Env(env))
+		gk.envs = append(gk.envs, Env(env))
+	}
+	// 未指定环境时, 为所有存在配置文件的环境生成
+	if len(gk.envs) == 0 {
+		for _, env := range supportsRuntimeEnvs() {
+			if _, err := os.Stat(filepath.Join(gk.workingDir, "configs", env.ConfigFile())); err == nil {
+				gk.envs = append(gk.envs, env)
+			}
+		}
+	}
+	if len(gk.envs) == 0 {
+		fmt.Println("🚫 No config file found in the 'configs' directory...")
+		os.Exit(1)
+		return
+	}
+	return
+}
+
+func (gk *genK8sCmd) run() {
+	modulePath, err := helpers.ModulePath(filepath.Join(gk.workingDir, "go.mod"))
+	if err != nil {
+		fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", gk.cmd.Use, err)
+		os.Exit(1)
+		return
+	}
+	base := k8sBaseData{
+		App:      dns1123Name(filepath.Base(modulePath)),
+		Image:    gk.image,
+		Port:     gk.port,
+		Replicas: gk.replicas,
+	}
+	if len(base.Image) == 0 {
+		base.Image = base.App + ":latest"
+	}
+	for _, task := range gk.Manifest().Cron.Tasks {
+		base.Tasks = append(base.Tasks, k8sTask{
+			Name:     dns1123Name(task.Name),
+			Schedule: task.Schedule,
+			Args:     task.Args(),
+		})
+	}
+	files := append([]genFile{}, k8sBaseFiles...)
+	if len(base.Tasks) > 0 {
+		files = append(files, k8sCronJobFile)
+	} else {
+		fmt.Printf("💡 No cron tasks declared in 'aurora.yaml' (cron.tasks), CronJobs are skipped...\n")
+	}
+	fmt.Printf("☸️ Generating Kubernetes manifests for [%s]...\n", color.GreenString(base.App))
+	if err = renderGenFiles(gk.outputDir, files, base, gk.force); err != nil {
+		fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", gk.cmd.Use, err)
+		os.Exit(1)
+		return
+	}
+	for _, env := range gk.envs {
+		if err = gk.renderOverlay(base, env); err != nil {
+			fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", gk.cmd.Use, err)
+			os.Exit(1)
+			return
+		}
+	}
+	// 本地校验生成的文件
+	if errs := kube.Validate(gk.outputDir); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Printf("❌ %v\n", err)
+		}
+		fmt.Printf("🚫 %d validation error(s) found in [%s]...\n", len(errs), gk.outputDir)
+		os.Exit(1)
+		return
+	}
+	fmt.Printf("\n✅ Manifests validated, apply with: %s\n", color.GreenString("kubectl apply -k %s", filepath.Join(gk.outputDir, "overlays", gk.envs[0].ToString())))
+	fmt.Printf("⚠️ 'secret.env' contains plaintext passwords, it is ignored by git, do not commit it to the repository...\n")
+	return
+}
+
+// 生成 overlays/<env>, ConfigMap 为 config.yaml 与 config.<env>.yaml 合并后的配置, 配置中的密码拆分到 secret.env 中由 secretGenerator 生成 Secret
+func (gk *genK8sCmd) renderOverlay(base k8sBaseData, env Env) error {
+	configFile := conf.ConfigFileName(env.ToString())
+	layered, err := conf.LoadLayered(conf.LoadOptions{Dir: filepath.Join(gk.workingDir, "configs"), Env: env.ToString(), EnvFile: env.ConfigFile()})
+	if err != nil {
+		return err
+	}
+	content, err := layered.YAML()
+	if err != nil {
+		return err
+	}
+	config, secrets, err := kube.SplitSecrets(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configFile, err)
+	}
+	for key, value := range secrets {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%s: %s: multi-line passwords are not supported in secret.env", configFile, key)
+		}
+	}
+	data := k8sOverlayData{
+		App:        base.App,
+		Image:      base.Image,
+		Env:        env.ToString(),
+		ConfigFile: configFile,
+		Config:     string(config),
+		Secrets:    secrets,
+	}
+	return renderGenFiles(filepath.Join(gk.outputDir, "overlays", env.ToString()), k8sOverlayFiles, data, gk.force)
+}
+
+// dns1123Name 转换为Kubernetes资源名称
+func dns1123Name(name string) string {
+	name = dns1123Cleaner.ReplaceAllString(strings.ToLower(name), "-")
+	return strings.Trim(name, "-")
+}
+
+func addGenK8sRuntimeFlag(cmd *cobra.Command, persistent bool) {
+	getFlags(cmd, persistent).StringSliceP(flagK8sEnvs.name, flagK8sEnvs.shortName, flagK8sEnvs.defaultValue.([]string), flagK8sEnvs.usage)
+	registerEnvCompletion(cmd, flagK8sEnvs.name)
+	getFlags(cmd, persistent).StringP(flagK8sOutput.name, flagK8sOutput.shortName, flagK8sOutput.defaultValue.(string), flagK8sOutput.usage)
+	getFlags(cmd, persistent).String(flagK8sImage.name, flagK8sImage.defaultValue.(string), flagK8sImage.usage)
+	getFlags(cmd, persistent).Int(flagK8sPort.name, flagK8sPort.defaultValue.(int), flagK8sPort.usage)
+	getFlags(cmd, persistent).Int(flagK8sReplicas.name, flagK8sReplicas.defaultValue.(int), flagK8sReplicas.usage)
+	getFlags(cmd, persistent).BoolP(flagGenForce.name, flagGenForce.shortName, flagGenForce.defaultValue.(bool), flagGenForce.usage)
+}
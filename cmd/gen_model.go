@@ -11,8 +11,10 @@ import (
 	"github.com/stubborn-gaga-0805/aurora/conf"
 	"github.com/stubborn-gaga-0805/aurora/consts"
 	"github.com/stubborn-gaga-0805/aurora/pkg/mysql"
+	"github.com/stubborn-gaga-0805/aurora/pkg/tools"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -64,6 +66,7 @@ func newGenModelCmd() *genModelCmd {
 		},
 	}
 	addGenModelRuntimeFlag(gen.cmd, true)
+	addConfigSetFlag(gen.cmd, true)
 
 	return gen
 }
@@ -78,7 +81,8 @@ func (gen *genModelCmd) initJobRuntime(cmd *cobra.Command) {
 
 	gen.id, _ = os.Hostname()
 	gen.env = Env(os.Getenv(consts.OSEnvKey))
-	gen.configFilePath = fmt.Sprintf("./configs/config.%s.yaml", gen.env)
+	gen.configFilePath = filepath.Join("./configs", gen.env.ConfigFile())
+	gen.sets = getConfigSets(cmd)
 	gen.genModelFlags = &genModelFlags{
 		flagTables:      getTables(cmd),
 		flagPackageName: getPackageName(cmd),
@@ -212,7 +216,7 @@ func (gen *genModelCmd) genModelProcess() error {
 	if err != nil {
 		return err
 	}
-	command := exec.Command("gentool",
+	command := exec.Command(tools.Lookup(wd, "gentool"),
 		"-dsn", dns,
 		"-db", "mysql",
 		"-tables", strings.Join(gen.chooseTables, ","),
@@ -225,7 +229,7 @@ func (gen *genModelCmd) genModelProcess() error {
 		"-fieldNullable",
 	)
 	command.Dir = wd
-	command.Env = os.Environ()
+	command.Env = gen.Environ()
 
 	stdout, _ := command.StdoutPipe()
 	stderr, _ := command.StderrPipe()
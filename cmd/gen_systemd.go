@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/systemd"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type genSystemdCmd struct {
+	*baseCmd
+
+	outputDir  string
+	force      bool
+	appName    string
+	appVersion string
+	user       string
+	installDir string
+	restart    string
+	serverArgs []string
+}
+
+// systemdData systemd unit 模板的数据
+type systemdData struct {
+	App              string
+	Env              string
+	User             string
+	InstallDir       string
+	EnvironmentFiles []string
+	ExecStart        string
+	Restart          string
+	Hardening        []string
+	Task             systemdTask
+}
+
+// systemdTask 定时任务对应的 service 和 timer
+type systemdTask struct {
+	Name       string
+	Unit       string
+	Schedule   string
+	OnCalendar string
+	ExecStart  string
+}
+
+var (
+	flagSystemdOutput     = flag{"output", "o", "deploy/systemd", "The directory of the generated files"}
+	flagSystemdName       = flag{"name", "n", "", "Set application name (default: the base name of the module)"}
+	flagSystemdUser       = flag{"user", "u", "", "The user (and group) to run the service as (default: the application name)"}
+	flagSystemdInstallDir = flag{"install-dir", "", "", "The directory the project is deployed to (default: /opt/<app>)"}
+	flagSystemdRestart    = flag{"restart", "", "on-failure", "The restart policy of the service, one of: " + strings.Join(systemdRestartPolicies, ", ")}
+
+	systemdRestartPolicies = []string{"no", "always", "on-success", "on-failure", "on-abnormal", "on-abort", "on-watchdog"}
+
+	// systemdHardening service 的安全加固选项, 服务只能写入 logs 目录
+	systemdHardening = []string{
+		"NoNewPrivileges=yes",
+		"PrivateTmp=yes",
+		"PrivateDevices=yes",
+		"ProtectSystem=strict",
+		"ProtectHome=yes",
+		"ProtectKernelTunables=yes",
+		"ProtectKernelModules=yes",
+		"ProtectKernelLogs=yes",
+		"ProtectControlGroups=yes",
+		"ProtectClock=yes",
+		"ProtectHostname=yes",
+		"RestrictSUIDSGID=yes",
+		"RestrictRealtime=yes",
+		"RestrictNamespaces=yes",
+		"RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6",
+		"LockPersonality=yes",
+		"MemoryDenyWriteExecute=yes",
+		"SystemCallArchitectures=native",
+		"CapabilityBoundingSet=",
+		"UMask=0027",
+	}
+)
+
+func newGenSystemdCmd() *genSystemdCmd {
+	gs := &genSystemdCmd{baseCmd: newBaseCmd()}
+	gs.cmd = &cobra.Command{
+		Use:   "systemd",
+		Short: "Generate systemd service units for the server and timer units for the cron tasks",
+		Long:  "💡 Generate systemd service units for the server and timer units for the cron tasks, eg: aurora gen systemd -e prod --with.ws --user app",
+		Run: func(cmd *cobra.Command, args []string) {
+			gs.initGenSystemdRuntime(cmd)
+			gs.run()
+		},
+	}
+	addGenSystemdRuntimeFlag(gs.cmd, true)
+	if gs.manifestErr == nil {
+		addManifestRuntimeFlag(gs.cmd, gs.manifest.Runtime.Args)
+	}
+
+	return gs
+}
+
+func (gs *genSystemdCmd) initGenSystemdRuntime(cmd *cobra.Command) {
+	// 检查是否在项目目录下
+	if !gs.InProjectPath() {
+		fmt.Println("🚫 The 'main.go' file is not found in the current directory, please run it in the project root directory...")
+		os.Exit(1)
+		return
+	}
+	gs.env = getGenEnv(cmd)
+	if !gs.env.Check() {
+		fmt.Printf("🚫 Unsupported operating environment [%s]...\n", gs.env)
+		os.Exit(1)
+		return
+	}
+	gs.outputDir = cmd.Flag(flagSystemdOutput.name).Value.String()
+	gs.force = getGenForce(cmd)
+	gs.appName = cmd.Flag(flagSystemdName.name).Value.String()
+	gs.appVersion = getAppVersion(cmd).ToString()
+	gs.user = cmd.Flag(flagSystemdUser.name).Value.String()
+	gs.installDir = cmd.Flag(flagSystemdInstallDir.name).Value.String()
+	gs.restart = cmd.Flag(flagSystemdRestart.name).Value.String()
+	if !lo.Contains(systemdRestartPolicies, gs.restart) {
+		fmt.Printf("🚫 Unsupported restart policy [%s], must be one of: %s\n", gs.restart, strings.Join(systemdRestartPolicies, ", "))
+		os.Exit(1)
+		return
+	}
+	if len(gs.appName) == 0 {
+		modulePath, err := helpers.ModulePath(filepath.Join(gs.workingDir, "go.mod"))
+		if err != nil {
+			fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", gs.cmd.Use, err)
+			os.Exit(1)
+			return
+		}
+		gs.appName = dns1123Name(filepath.Base(modulePath))
+	}
+	if len(gs.user) == 0 {
+		gs.user = gs.appName
+	}
+	if len(gs.installDir) == 0 {
+		gs.installDir = "/opt/" + gs.appName
+	}
+	if !filepath.IsAbs(gs.installDir) {
+		fmt.Printf("🚫 The install directory [%s] must be an absolute path...\n", gs.installDir)
+		os.Exit(1)
+		return
+	}
+	// 服务的启动参数, 与 aurora run 保持一致
+	for _, f := range []flag{flagWithCronJob, flagWithWs, flagWithoutHttp, flagWithoutMQ} {
+		if enabled, _ := cmd.Flags().GetBool(f.name); enabled {
+			gs.serverArgs = append(gs.serverArgs, fmt.Sprintf("--%s", f.name))
+		}
+	}
+	gs.serverArgs = append(gs.serverArgs, getManifestRuntimeArgs(cmd, gs.Manifest().Runtime.Args)...)
+	return
+}
+
+func (gs *genSystemdCmd) run() {
+	var (
+		bin  = filepath.Join(gs.installDir, "bin/server")
+		data = systemdData{
+			App:        gs.appName,
+			Env:        gs.env.ToString(),
+			User:       gs.user,
+			InstallDir: gs.installDir,
+			EnvironmentFiles: []string{
+				filepath.Join(gs.installDir, ".env"),
+				filepath.Join(gs.installDir, fmt.Sprintf(".env.%s", gs.env)),
+			},
+			Restart:   gs.restart,
+			Hardening: append(append([]string{}, systemdHardening...), "ReadWritePaths=-"+filepath.Join(gs.installDir, "logs")),
+		}
+		args = []string{
+			"run",
+			"-c", filepath.Join(gs.installDir, "configs", gs.env.ConfigFile()),
+			"-e", gs.env.ToString(),
+			fmt.Sprintf("--%s", flagAppName.name), gs.appName,
+			fmt.Sprintf("--%s", flagAppVersion.name), gs.appVersion,
+		}
+		tasks = gs.Manifest().Cron.Tasks
+	)
+	data.ExecStart = systemdJoin(append(append([]string{bin}, args...), gs.serverArgs...))
+	// 写入文件前先转换所有定时任务的执行时间
+	calendars := make([]string, len(tasks))
+	for i, task := range tasks {
+		onCalendar, err := systemd.OnCalendar(task.Schedule)
+		if err != nil {
+			fmt.Printf("🚫 Failed to convert the schedule of cron task [%s]...[%v]\n", task.Name, err)
+			os.Exit(1)
+			return
+		}
+		calendars[i] = onCalendar
+	}
+
+	fmt.Printf("🐧 Generating systemd units for [%s] in env [%s]...\n", color.GreenString(data.App), color.GreenString(data.Env))
+	files := []genFile{{path: data.App + ".service", template: "templates/systemd/service.tmpl"}}
+	if err := renderGenFiles(gs.outputDir, files, data, gs.force); err != nil {
+		fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", gs.cmd.Use, err)
+		os.Exit(1)
+		return
+	}
+	units := []string{data.App + ".service"}
+	for i, task := range tasks {
+		data.Task = systemdTask{
+			Name:       task.Name,
+			Unit:       fmt.Sprintf("%s-%s", data.App, dns1123Name(task.Name)),
+			Schedule:   task.Schedule,
+			OnCalendar: calendars[i],
+			ExecStart:  systemdJoin(append([]string{bin}, task.Args()...)),
+		}
+		files = []genFile{
+			{path: data.Task.Unit + ".service", template: "templates/systemd/task.service.tmpl"},
+			{path: data.Task.Unit + ".timer", template: "templates/systemd/task.timer.tmpl"},
+		}
+		if err := renderGenFiles(gs.outputDir, files, data, gs.force); err != nil {
+			fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", gs.cmd.Use, err)
+			os.Exit(1)
+			return
+		}
+		units = append(units, data.Task.Unit+".timer")
+	}
+	if len(tasks) == 0 {
+		fmt.Printf("💡 No cron tasks declared in 'aurora.yaml' (cron.tasks), timers are skipped...\n")
+	} else if lo.Contains(gs.serverArgs, fmt.Sprintf("--%s", flagWithCronJob.name)) {
+		fmt.Printf("⚠️ The cron tasks run both in the server (--%s) and by the timers, make sure that is intended...\n", flagWithCronJob.name)
+	}
+	fmt.Printf("\n💡 Deploy the project to [%s], then install the units with:\n", data.InstallDir)
+	fmt.Printf("   %s\n", color.GreenString("sudo cp %s/*.service %s/*.timer /etc/systemd/system/", gs.outputDir, gs.outputDir))
+	fmt.Printf("   %s\n", color.GreenString("sudo systemctl daemon-reload && sudo systemctl enable --now %s", strings.Join(units, " ")))
+	return
+}
+
+// systemdJoin 拼接 ExecStart 的命令行, 包含空白或引号的参数使用双引号, 转义 systemd 的 "%" 和 "$"
+func systemdJoin(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+		if len(arg) == 0 || strings.ContainsAny(arg, " \t\"'\\;") {
+			arg = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func addGenSystemdRuntimeFlag(cmd *cobra.Command, persistent bool) {
+	getFlags(cmd, persistent).StringP(flagGenEnv.name, flagGenEnv.shortName, flagGenEnv.defaultValue.(string), flagGenEnv.usage)
+	registerEnvCompletion(cmd, flagGenEnv.name)
+	getFlags(cmd, persistent).StringP(flagSystemdOutput.name, flagSystemdOutput.shortName, flagSystemdOutput.defaultValue.(string), flagSystemdOutput.usage)
+	getFlags(cmd, persistent).BoolP(flagGenForce.name, flagGenForce.shortName, flagGenForce.defaultValue.(bool), flagGenForce.usage)
+	getFlags(cmd, persistent).StringP(flagSystemdName.name, flagSystemdName.shortName, flagSystemdName.defaultValue.(string), flagSystemdName.usage)
+	getFlags(cmd, persistent).StringP(flagAppVersion.name, flagAppVersion.shortName, flagAppVersion.defaultValue.(string), flagAppVersion.usage)
+	getFlags(cmd, persistent).StringP(flagSystemdUser.name, flagSystemdUser.shortName, flagSystemdUser.defaultValue.(string), flagSystemdUser.usage)
+	getFlags(cmd, persistent).String(flagSystemdInstallDir.name, flagSystemdInstallDir.defaultValue.(string), flagSystemdInstallDir.usage)
+	getFlags(cmd, persistent).String(flagSystemdRestart.name, flagSystemdRestart.defaultValue.(string), flagSystemdRestart.usage)
+	getFlags(cmd, persistent).Bool(flagWithCronJob.name, flagWithCronJob.defaultValue.(bool), flagWithCronJob.usage)
+	getFlags(cmd, persistent).Bool(flagWithWs.name, flagWithWs.defaultValue.(bool), flagWithWs.usage)
+	getFlags(cmd, persistent).Bool(flagWithoutHttp.name, flagWithoutHttp.defaultValue.(bool), flagWithoutHttp.usage)
+	getFlags(cmd, persistent).Bool(flagWithoutMQ.name, flagWithoutMQ.defaultValue.(bool), flagWithoutMQ.usage)
+}
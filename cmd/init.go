@@ -1,20 +1,84 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/cheggaaa/pb/v3"
 	"github.com/fatih/color"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	"github.com/stubborn-gaga-0805/aurora/consts"
 	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/manifest"
+	"github.com/stubborn-gaga-0805/aurora/pkg/tools"
 	"os"
 	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 )
 
 type initCmd struct {
 	*baseCmd
 	goModPath string
+	check     bool
+	jobs      int
+	timeout   time.Duration
+	only      []string
+	skip      []string
+	tools     []manifest.Tool
 }
 
+// initStep aurora init 的一个步骤, 通过 --only、--skip 按名称过滤
+type initStep struct {
+	name    string
+	tool    *manifest.Tool
+	command func(ctx context.Context) (*exec.Cmd, error)
+}
+
+type initResult struct {
+	step     initStep
+	status   initStatus
+	duration time.Duration
+	stderr   string
+	note     string
+	err      error
+}
+
+type initStatus string
+
+const (
+	initStepTidy   = "tidy"
+	initStepVerify = "verify"
+
+	initStatusOK      initStatus = "ok"
+	initStatusSkipped initStatus = "skipped"
+	initStatusFailed  initStatus = "failed"
+	initStatusTimeout initStatus = "timeout"
+)
+
+var (
+	flagInitCheck   = flag{"check", "", false, "Only report the tools that are missing or differ from the declared versions"}
+	flagInitJobs    = flag{"jobs", "j", 4, "Number of tools to install in parallel"}
+	flagInitTimeout = flag{"timeout", "", 5 * time.Minute, "Timeout of each step"}
+	flagInitOnly    = flag{"only", "", []string{}, "Only run the steps with the names (tool names, 'tidy' and 'verify'), eg: --only wire,tidy"}
+	flagInitSkip    = flag{"skip", "", []string{}, "Skip the steps with the names (tool names, 'tidy' and 'verify')"}
+
+	// defaultTools 项目没有声明工具时安装的工具 (未固定版本)
+	defaultTools = []manifest.Tool{
+		{Package: "github.com/google/wire/cmd/wire", Version: manifest.VersionLatest},
+		{Package: "google.golang.org/protobuf/cmd/protoc-gen-go", Version: manifest.VersionLatest},
+		{Package: "google.golang.org/grpc/cmd/protoc-gen-go-grpc", Version: manifest.VersionLatest},
+		{Package: "github.com/google/gnostic/cmd/protoc-gen-openapi", Version: manifest.VersionLatest},
+		{Package: "gorm.io/gen/tools/gentool", Version: manifest.VersionLatest},
+	}
+)
+
 func newInitCmd() *initCmd {
 	init := &initCmd{
 		baseCmd: newBaseCmd(),
@@ -23,17 +87,22 @@ func newInitCmd() *initCmd {
 		Use:     "init",
 		Aliases: []string{},
 		Short:   "Initialize the project",
-		Long:    "💡 Initialize the project, eg: aurora init",
+		Long:    "💡 Initialize the project, install the tools declared in 'aurora.yaml' (or 'tools.go') into ./bin/tools, eg: aurora init --check",
 		Run: func(cmd *cobra.Command, args []string) {
-			init.initInitRuntime()
+			init.initInitRuntime(cmd)
 			init.run()
 		},
 	}
+	getFlags(init.cmd, false).Bool(flagInitCheck.name, flagInitCheck.defaultValue.(bool), flagInitCheck.usage)
+	getFlags(init.cmd, false).IntP(flagInitJobs.name, flagInitJobs.shortName, flagInitJobs.defaultValue.(int), flagInitJobs.usage)
+	getFlags(init.cmd, false).Duration(flagInitTimeout.name, flagInitTimeout.defaultValue.(time.Duration), flagInitTimeout.usage)
+	getFlags(init.cmd, false).StringSlice(flagInitOnly.name, flagInitOnly.defaultValue.([]string), flagInitOnly.usage)
+	getFlags(init.cmd, false).StringSlice(flagInitSkip.name, flagInitSkip.defaultValue.([]string), flagInitSkip.usage)
 
 	return init
 }
 
-func (init *initCmd) initInitRuntime() {
+func (init *initCmd) initInitRuntime(cmd *cobra.Command) {
 	var err error
 	// 检查是否在项目目录下
 	if !init.InProjectPath() {
@@ -53,42 +122,274 @@ func (init *initCmd) initInitRuntime() {
 	}
 	init.id, _ = os.Hostname()
 	init.env = Env(os.Getenv(consts.OSEnvKey))
+	init.check, _ = cmd.Flags().GetBool(flagInitCheck.name)
+	init.jobs, _ = cmd.Flags().GetInt(flagInitJobs.name)
+	init.timeout, _ = cmd.Flags().GetDuration(flagInitTimeout.name)
+	init.only, _ = cmd.Flags().GetStringSlice(flagInitOnly.name)
+	init.skip, _ = cmd.Flags().GetStringSlice(flagInitSkip.name)
+	if init.timeout <= 0 {
+		fmt.Printf("🚫 --%s must be greater than 0...\n", flagInitTimeout.name)
+		os.Exit(1)
+		return
+	}
+	var declared bool
+	if init.tools, declared, err = projectTools(init.workingDir, init.Manifest()); err != nil {
+		fmt.Printf("🚫 Failed to read the tools from 'tools.go'...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	if !declared {
+		fmt.Printf("⚠️ No tools are declared in '%s' or 'tools.go', the default tools are installed at %s versions...\n", manifest.FileName, color.YellowString(manifest.VersionLatest))
+	}
+	// 检查 --only、--skip 中的步骤名
+	names := []string{initStepTidy, initStepVerify}
+	for _, tool := range init.tools {
+		names = append(names, tool.Binary())
+	}
+	for _, name := range append(append([]string{}, init.only...), init.skip...) {
+		if !lo.Contains(names, name) {
+			fmt.Printf("🚫 Unknown step [%s], the steps are: %s\n", name, strings.Join(names, ", "))
+			os.Exit(1)
+			return
+		}
+	}
 	return
 }
 
+// projectTools 项目需要的工具, 优先从 aurora.yaml 读取, 其次是 tools.go, 都没有声明时返回默认的工具
+func projectTools(workingDir string, m *manifest.Manifest) (list []manifest.Tool, declared bool, err error) {
+	if len(m.Tools) > 0 {
+		return m.Tools, true, nil
+	}
+	if list, err = tools.FromToolsGo(workingDir); err != nil || len(list) > 0 {
+		return list, true, err
+	}
+	return defaultTools, false, nil
+}
+
+// selected 步骤是否需要执行
+func (init *initCmd) selected(name string) bool {
+	if len(init.only) > 0 && !lo.Contains(init.only, name) {
+		return false
+	}
+	return !lo.Contains(init.skip, name)
+}
+
 func (init *initCmd) run() {
+	if init.check {
+		init.runCheck()
+		return
+	}
+	// 中断时终止正在执行的命令
+	ctx, stop := signal.NotifyContext(init.ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	init.ctx = ctx
+
 	var (
-		success = 0
-		cmdList = []*exec.Cmd{
-			exec.Command("go", "install", "github.com/google/wire/cmd/wire@latest"),
-			exec.Command("go", "install", "google.golang.org/protobuf/cmd/protoc-gen-go@latest"),
-			exec.Command("go", "install", "google.golang.org/grpc/cmd/protoc-gen-go-grpc@latest"),
-			exec.Command("go", "install", "github.com/google/gnostic/cmd/protoc-gen-openapi@latest"),
-			exec.Command("go", "install", "gorm.io/gen/tools/gentool@latest"),
-			exec.Command("go", "mod", "tidy"),
-			exec.Command("go", "mod", "verify"),
-		}
+		installs []initStep
+		results  []initResult
 	)
-	bar := helpers.NewProgressBar(len(cmdList), "Initializing project...")
-	for _, cmd := range cmdList {
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("\n🚫 %v %s [%v]\n", cmd.Args, color.RedString("execution failed!"), err)
+	for _, tool := range init.tools {
+		if !init.selected(tool.Binary()) {
 			continue
-		} else {
-			success++
-			fmt.Printf("\n✅ %v %s", cmd.Args, color.GreenString("all execution succeed!"))
-			bar.Increment()
 		}
+		tool := tool
+		installs = append(installs, initStep{name: tool.Binary(), tool: &tool, command: func(ctx context.Context) (*exec.Cmd, error) {
+			return tools.InstallCommand(ctx, init.workingDir, tool)
+		}})
 	}
-	if success != len(cmdList) {
-		fmt.Printf("\n\n‼️ %s", color.YellowString("partial success!"))
-		os.Exit(1)
+	var modSteps []initStep
+	if init.selected(initStepTidy) {
+		modSteps = append(modSteps, initStep{name: initStepTidy, command: init.goCommand("mod", "tidy")})
+	}
+	if init.selected(initStepVerify) {
+		modSteps = append(modSteps, initStep{name: initStepVerify, command: init.goCommand("mod", "verify")})
+	}
+	total := len(installs) + len(modSteps)
+	if total == 0 {
+		fmt.Println("💡 No steps to run...")
 		return
 	}
+	fmt.Printf("⚙️ Initializing project, %d step(s) with %d job(s)...\n", total, init.jobs)
+	bar := helpers.NewProgressBar(total, "Initializing project...")
+	// 并行安装工具, 之后依次执行 go mod tidy、go mod verify
+	results = append(results, init.runSteps(installs, init.jobs, bar)...)
+	results = append(results, init.runSteps(modSteps, 1, bar)...)
 	bar.Finish()
-	fmt.Printf("\n\n🍺🍺🍺 Initialize the project successfully!\n")
 
+	failed := printInitSummary(results)
+	if len(failed) > 0 {
+		fmt.Printf("\n‼️ %d step(s) failed, rerun them with %s\n", len(failed), color.GreenString("aurora init --%s %s", flagInitOnly.name, strings.Join(failed, ",")))
+		os.Exit(1)
+		return
+	}
+	fmt.Printf("\n🍺🍺🍺 Initialize the project successfully! The tools are installed in %s\n", color.GreenString("./"+tools.Dir))
+	// protoc、Makefile 等通过 PATH 查找工具, aurora 执行的命令会自动添加该目录
+	fmt.Printf("💡 Commands run by aurora find them automatically, add them to PATH for protoc, make, etc: %s\n", color.GreenString(`export PATH="$PWD/%s:$PATH"`, tools.Dir))
+
+	return
+}
+
+// runSteps 使用有限的并发数执行步骤, 结果的顺序与步骤一致
+func (init *initCmd) runSteps(steps []initStep, jobs int, bar *pb.ProgressBar) []initResult {
+	var (
+		wg      sync.WaitGroup
+		queue   = make(chan int)
+		results = make([]initResult, len(steps))
+	)
+	if jobs <= 0 {
+		jobs = 1
+	}
+	for i := 0; i < jobs && i < len(steps); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range queue {
+				results[idx] = init.runStep(steps[idx])
+				bar.Increment()
+			}
+		}()
+	}
+	for idx := range steps {
+		queue <- idx
+	}
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+// runStep 执行单个步骤并缓存输出, 已安装相同版本的工具直接跳过
+func (init *initCmd) runStep(step initStep) (result initResult) {
+	result.step = step
+	if step.tool != nil {
+		if check, err := tools.CheckTool(init.workingDir, *step.tool); err == nil && check.Status == tools.StatusOK {
+			result.status = initStatusSkipped
+			result.note = check.Installed + " is already installed"
+			return result
+		}
+	}
+	var (
+		start       = time.Now()
+		ctx, cancel = context.WithTimeout(init.ctx, init.timeout)
+		stderr      bytes.Buffer
+	)
+	defer cancel()
+	cmd, err := step.command(ctx)
+	if err != nil {
+		result.status, result.err = initStatusFailed, err
+		return result
+	}
+	cmd.Stderr = &stderr
+	// 命令的子进程退出后才会关闭输出, 超时后最多等待的时间
+	cmd.WaitDelay = 3 * time.Second
+	result.err = cmd.Run()
+	result.duration = time.Since(start)
+	result.stderr = strings.TrimSpace(stderr.String())
+	result.status = initStatusOK
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.status, result.err = initStatusTimeout, fmt.Errorf("timed out after %s", init.timeout)
+	case result.err != nil:
+		result.status = initStatusFailed
+	}
+	return result
+}
+
+func (init *initCmd) goCommand(args ...string) func(ctx context.Context) (*exec.Cmd, error) {
+	return func(ctx context.Context) (*exec.Cmd, error) {
+		cmd := exec.CommandContext(ctx, "go", args...)
+		cmd.Dir = init.workingDir
+		return cmd, nil
+	}
+}
+
+// printInitSummary 打印每个步骤的结果以及失败步骤的错误输出, 返回失败的步骤
+func printInitSummary(results []initResult) (failed []string) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STEP\tCOMMAND\tDURATION\tSTATUS\tNOTE")
+	for _, result := range results {
+		var (
+			status = color.GreenString(string(result.status))
+			note   = result.note
+		)
+		switch result.status {
+		case initStatusSkipped:
+			status = color.HiBlackString(string(result.status))
+		case initStatusFailed, initStatusTimeout:
+			failed = append(failed, result.step.name)
+			status = color.RedString(string(result.status))
+			note = lastLine(result.stderr)
+			if len(note) == 0 && result.err != nil {
+				note = result.err.Error()
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.step.name, result.step.String(), result.duration.Round(time.Millisecond), status, note)
+	}
+	_ = w.Flush()
+	for _, result := range results {
+		if result.status == initStatusFailed || result.status == initStatusTimeout {
+			fmt.Printf("\n🚫 [%s] %s...[%v]\n", result.step.name, result.status, result.err)
+			if len(result.stderr) > 0 {
+				fmt.Println(color.HiBlackString(result.stderr))
+			}
+		}
+	}
+	return failed
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// String 步骤执行的命令
+func (step initStep) String() string {
+	switch {
+	case step.tool != nil && step.tool.InModule:
+		return "go install " + step.tool.Package
+	case step.tool != nil:
+		return "go install " + step.tool.Package + "@" + step.tool.Version
+	case step.name == initStepTidy:
+		return "go mod tidy"
+	}
+	return "go mod verify"
+}
+
+// runCheck 列出工具的安装状态, 有缺失或版本不一致的工具时退出码为1
+func (init *initCmd) runCheck() {
+	var (
+		drift = 0
+		w     = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	)
+	fmt.Fprintln(w, "TOOL\tPACKAGE\tWANT\tINSTALLED\tSTATUS")
+	for _, tool := range init.tools {
+		if !init.selected(tool.Binary()) {
+			continue
+		}
+		check, err := tools.CheckTool(init.workingDir, tool)
+		if err != nil {
+			fmt.Printf("🚫 Failed to read the version of [%s]...[%v]\n", tool.Binary(), err)
+			os.Exit(1)
+			return
+		}
+		status := color.GreenString(string(check.Status))
+		switch check.Status {
+		case tools.StatusMissing, tools.StatusDrift:
+			drift++
+			status = color.RedString(string(check.Status))
+		case tools.StatusUnpinned:
+			status = color.YellowString(string(check.Status))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", tool.Binary(), tool.Package, tool.Version, check.Installed, status)
+	}
+	_ = w.Flush()
+	if drift > 0 {
+		fmt.Printf("\n‼️ %d tool(s) are missing or differ from the declared versions, run %s to install them...\n", drift, color.GreenString("aurora init"))
+		os.Exit(1)
+		return
+	}
+	fmt.Printf("\n✅ All tools match the declared versions...\n")
 	return
 }
@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
-	"github.com/stubborn-gaga-0805/aurora/consts"
 	"os"
 	"os/exec"
 )
@@ -16,6 +15,7 @@ type jobCmd struct {
 type jobFlags struct {
 	flagParams   string
 	flagShowList bool
+	confirmYes   bool
 }
 
 var (
@@ -34,16 +34,20 @@ func newJobCmd() *jobCmd {
 		Short:   "Customer Task Related Commands",
 		Long:    `💡 Customer Task Related Commands, eg: aurora job myJob -p "first_param,second_param,third_param"`,
 		Run: func(cmd *cobra.Command, args []string) {
-			jc.initJobRuntime(cmd)
+			jc.initJobRuntime(cmd, args)
 			jc.run(args)
 		},
 	}
 	addJobRuntimeFlag(jc.cmd, true)
+	addEnvFileFlag(jc.cmd, true)
+	addForceBuildFlag(jc.cmd, true)
+	addProfileFlag(jc.cmd, true)
+	addConfirmYesFlag(jc.cmd, true)
 
 	return jc
 }
 
-func (jc *jobCmd) initJobRuntime(cmd *cobra.Command) {
+func (jc *jobCmd) initJobRuntime(cmd *cobra.Command, args []string) {
 	// 检查是否在项目目录下
 	if !jc.InProjectPath() {
 		fmt.Println("🚫 The 'main.go' file is not found in the current directory, please run it in the project root directory...")
@@ -51,11 +55,20 @@ func (jc *jobCmd) initJobRuntime(cmd *cobra.Command) {
 		return
 	}
 	jc.id, _ = os.Hostname()
-	jc.env = Env(os.Getenv(consts.OSEnvKey))
+	jc.resolveEnv(cmd)
 	jc.jobFlags = &jobFlags{
 		flagParams:   getParams(cmd),
 		flagShowList: getShowList(cmd),
+		confirmYes:   getConfirmYes(cmd),
 	}
+	// 受保护的环境中执行任务前需要确认 (在编译之前)
+	if !jc.flagShowList && len(args) > 0 && !confirmProtectedEnv(jc.env, fmt.Sprintf("run the job [%s]", args[0]), jc.confirmYes) {
+		fmt.Println("🚫 The job is not executed...")
+		os.Exit(1)
+		return
+	}
+	jc.forceBuild = getForceBuild(cmd)
+	jc.initProfile(cmd, jc.Manifest().Build.RunProfile)
 	return
 }
 
@@ -73,6 +86,7 @@ func (jc *jobCmd) run(args []string) {
 		goArgs = append(goArgs, "job", "-n", args[0], "-p", jc.flagParams)
 	}
 	fd := exec.Command(bin, goArgs...)
+	fd.Env = jc.Environ()
 	fd.Stdout = os.Stdout
 	fd.Stderr = os.Stderr
 	if err := fd.Run(); err != nil {
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/modrename"
+	"os"
+	"path/filepath"
+)
+
+type renameModuleCmd struct {
+	*baseCmd
+
+	from   string
+	to     string
+	dryRun bool
+}
+
+var (
+	flagRenameFrom   = flag{"from", "", "", "The module path to replace (default: the module in 'go.mod')"}
+	flagRenameDryRun = flag{"dry-run", "", false, "Only list the files that would be changed"}
+)
+
+func newRenameModuleCmd() *renameModuleCmd {
+	rm := &renameModuleCmd{baseCmd: newBaseCmd()}
+	rm.cmd = &cobra.Command{
+		Use:   "rename-module <new-module>",
+		Short: "Rename the go module of the project and rewrite the imports, go_package options, Makefiles and YAML files",
+		Long:  "💡 Rename the go module of the project, eg: aurora rename-module github.com/org/app --dry-run",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			rm.initRenameModuleRuntime(cmd, args[0])
+			rm.run()
+		},
+	}
+	getFlags(rm.cmd, false).String(flagRenameFrom.name, flagRenameFrom.defaultValue.(string), flagRenameFrom.usage)
+	getFlags(rm.cmd, false).Bool(flagRenameDryRun.name, flagRenameDryRun.defaultValue.(bool), flagRenameDryRun.usage)
+
+	return rm
+}
+
+func (rm *renameModuleCmd) initRenameModuleRuntime(cmd *cobra.Command, to string) {
+	// 检查是否在项目目录下
+	if !rm.InProjectPath() {
+		fmt.Println("🚫 The 'main.go' file is not found in the current directory, please run it in the project root directory...")
+		os.Exit(1)
+		return
+	}
+	rm.to = to
+	rm.from = cmd.Flag(flagRenameFrom.name).Value.String()
+	rm.dryRun, _ = cmd.Flags().GetBool(flagRenameDryRun.name)
+	if len(rm.from) == 0 {
+		var err error
+		if rm.from, err = helpers.ModulePath(filepath.Join(rm.workingDir, "go.mod")); err != nil {
+			fmt.Printf("🚫 Failed to read the module path from 'go.mod'...[%v]\n", err)
+			os.Exit(1)
+			return
+		}
+	}
+	if rm.from == rm.to {
+		fmt.Printf("💡 The module is already [%s]...\n", rm.to)
+		os.Exit(0)
+	}
+	return
+}
+
+func (rm *renameModuleCmd) run() {
+	fmt.Printf("🔧 Renaming module [%s] to [%s]...\n", color.BlueString(rm.from), color.GreenString(rm.to))
+	report, err := renameModule(rm.workingDir, rm.from, rm.to, rm.dryRun)
+	if err != nil {
+		fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", rm.cmd.Use, err)
+		os.Exit(1)
+		return
+	}
+	if rm.dryRun {
+		fmt.Printf("\n💡 Dry run, %d file(s) would be changed...\n", len(report.Changes))
+		return
+	}
+	fmt.Printf("\n✅ %d file(s) changed, run %s to verify the project...\n", len(report.Changes), color.GreenString("go build ./..."))
+	return
+}
+
+// renameModule 重命名项目的模块路径, 并打印修改的文件
+func renameModule(dir, from, to string, dryRun bool) (*modrename.Report, error) {
+	report, err := modrename.Rename(dir, from, to, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range report.Changes {
+		fmt.Printf("💡 %s %s\n", change.Path, color.HiBlackString("(%d)", change.Count))
+	}
+	for _, warning := range report.Warnings {
+		fmt.Printf("⚠️ Skipped %s\n", warning)
+	}
+	return report, nil
+}
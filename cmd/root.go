@@ -48,6 +48,13 @@ func newRootCmd() *rootCmd {
 		newBuildCmd(),
 		newRunCmd(),
 		newJobCmd(),
+		newEnvCmd(),
+		newGenCmd(),
+		newTemplateCmd(),
+		newRenameModuleCmd(),
+		newUpgradeCmd(),
+		newDoctorCmd(),
+		newConfigCmd(),
 		//newCronCmd(),
 	)
 
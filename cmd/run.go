@@ -3,8 +3,13 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/stubborn-gaga-0805/aurora/pkg/dotenv"
+	"github.com/stubborn-gaga-0805/aurora/pkg/manifest"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 )
 
 type runCmd struct {
@@ -21,11 +26,12 @@ type runFlags struct {
 	withEtcdConfig bool
 	withoutHttp    bool
 	withoutMQ      bool
+	extraArgs      []string
 }
 
 var (
 	flagAppName        = flag{"name", "n", "prepare-to-go", "Set application name"}
-	flagAppEnvironment = flag{"env", "e", "local", "Set the operating environment of the application"}
+	flagAppEnvironment = flag{"env", "e", "", "Set the operating environment of the application (default: the first env declared in aurora.yaml, local)"}
 	flagAppVersion     = flag{"version", "v", "v1.0", "Set the version of the application"}
 	flagAppConfig      = flag{"config", "c", "", "Set the path to the configuration file"}
 	flagWithCronJob    = flag{"with.cron", "", false, "Whether to start the crontab task"}
@@ -40,14 +46,21 @@ func newRunCmd() *runCmd {
 		Use:     "run",
 		Aliases: []string{"start", "running", "up"},
 		Short:   "Start web server (such as: http, grpc, websocket), and start Http server by default",
-		Long:    `💡 Start your app... eg: aurora run -n myApp e test --with.cron --without.mq`,
+		Long:    `💡 Start your app... eg: aurora run -n myApp -e test --with.cron --without.mq -- --other-flag=value`,
 		Run: func(cmd *cobra.Command, args []string) {
 			run.initRuntime(cmd)
 			run.initConfig()
-			run.run()
+			run.run(getPassThroughArgs(cmd, args))
 		},
 	}
 	addServerRuntimeFlag(run.cmd, true)
+	if run.manifestErr == nil {
+		addManifestRuntimeFlag(run.cmd, run.manifest.Runtime.Args)
+	}
+	addEnvFileFlag(run.cmd, true)
+	addConfigSetFlag(run.cmd, true)
+	addForceBuildFlag(run.cmd, true)
+	addProfileFlag(run.cmd, true)
 
 	return run
 }
@@ -61,7 +74,6 @@ func (run *runCmd) initRuntime(cmd *cobra.Command) {
 		return
 	}
 	var (
-		err      error
 		appEnv   = getAppEnvironment(cmd)
 		runFlags = &runFlags{
 			appName:    string(getAppName(cmd)),
@@ -70,8 +82,14 @@ func (run *runCmd) initRuntime(cmd *cobra.Command) {
 	)
 	run.id, _ = os.Hostname()
 	run.env = appEnv
+	if len(appEnv) == 0 {
+		appEnv = defaultRuntimeEnv()
+		run.env = appEnv
+	}
 	if !appEnv.Check() {
-		panic(fmt.Sprintf("Unsupported operating environment... 【%s】", run.env))
+		fmt.Printf("🚫 Unsupported operating environment [%s], the supported environments are: %s\n", run.env, joinEnvs(supportsRuntimeEnvs()))
+		os.Exit(1)
+		return
 	}
 	run.isDebug = appEnv.IsDebug()
 	run.runFlags = runFlags
@@ -81,26 +99,32 @@ func (run *runCmd) initRuntime(cmd *cobra.Command) {
 	configPath := getAppConfigPath(cmd)
 	if configPath.UserDefined() {
 		run.configFilePath = configPath.ToString()
-	} else {
-		run.configFilePath = fmt.Sprintf("./configs/config.%s.yaml", run.env)
+		run.customConfig = true
 	}
+	run.sets = getConfigSets(cmd)
 	run.runFlags.withCronJob = getWithCronJob(run.cmd)
 	run.runFlags.withWs = getWithWs(run.cmd)
 	run.runFlags.withoutHttp = getWithOutHttp(run.cmd)
 	run.runFlags.withoutMQ = getWithoutMQConfig(run.cmd)
-	if err != nil {
-		panic(err)
-	}
+	run.runFlags.extraArgs = getManifestRuntimeArgs(run.cmd, run.Manifest().Runtime.Args)
+
+	// 加载env文件, 子进程的运行环境以 --env 为准
+	run.forceBuild = getForceBuild(cmd)
+	run.initProfile(cmd, run.Manifest().Build.RunProfile)
+	run.initEnvironment(cmd)
+	run.environ.Set(consts.OSEnvKey, run.env.ToString(), dotenv.SourceAurora)
 
 	return
 }
 
-func (run *runCmd) run() {
-	bin := run.Build()
+func (run *runCmd) run(passThroughArgs []string) {
+	bin := run.GetBin()
 	goArgs := []string{
 		"run",
-		"-c", run.configFilePath,
+		"-c", run.serverConfigFile(),
 		"-e", run.runFlags.appEnv,
+		fmt.Sprintf("--%s", flagAppName.name), run.runFlags.appName,
+		fmt.Sprintf("--%s", flagAppVersion.name), run.runFlags.appVersion,
 	}
 	if run.runFlags.withWs {
 		fmt.Printf("--%s\n", flagWithWs.name)
@@ -118,7 +142,11 @@ func (run *runCmd) run() {
 		fmt.Printf("--%s\n", flagWithoutMQ.name)
 		goArgs = append(goArgs, fmt.Sprintf("--%s", flagWithoutMQ.name))
 	}
+	// aurora.yaml 中声明的参数以及 "--" 之后的参数原样透传
+	goArgs = append(goArgs, run.runFlags.extraArgs...)
+	goArgs = append(goArgs, passThroughArgs...)
 	fd := exec.Command(bin, goArgs...)
+	fd.Env = run.Environ()
 	fd.Stdout = os.Stdout
 	fd.Stderr = os.Stderr
 	if err := fd.Run(); err != nil {
@@ -133,6 +161,7 @@ func (run *runCmd) run() {
 func addServerRuntimeFlag(cmd *cobra.Command, persistent bool) {
 	getFlags(cmd, persistent).StringP(flagAppName.name, flagAppName.shortName, flagAppName.defaultValue.(string), flagAppName.usage)
 	getFlags(cmd, persistent).StringP(flagAppEnvironment.name, flagAppEnvironment.shortName, flagAppEnvironment.defaultValue.(string), flagAppEnvironment.usage)
+	registerEnvCompletion(cmd, flagAppEnvironment.name)
 	getFlags(cmd, persistent).StringP(flagAppVersion.name, flagAppVersion.shortName, flagAppVersion.defaultValue.(string), flagAppVersion.usage)
 	getFlags(cmd, persistent).StringP(flagAppConfig.name, flagAppConfig.shortName, flagAppConfig.defaultValue.(string), flagAppConfig.usage)
 	getFlags(cmd, persistent).BoolP(flagWithCronJob.name, flagWithCronJob.shortName, flagWithCronJob.defaultValue.(bool), flagWithCronJob.usage)
@@ -203,8 +232,67 @@ func getWithoutMQConfig(cmd *cobra.Command) bool {
 		withoutMQ bool
 		err       error
 	)
-	if withoutMQ, err = cmd.Flags().GetBool(flagWithoutHttp.name); err != nil {
+	if withoutMQ, err = cmd.Flags().GetBool(flagWithoutMQ.name); err != nil {
 		panic(err)
 	}
 	return withoutMQ
 }
+
+// 注册项目配置(aurora.yaml)中声明的服务启动参数, 与内置参数冲突的会被忽略
+func addManifestRuntimeFlag(cmd *cobra.Command, args []manifest.Arg) {
+	flags := getFlags(cmd, true)
+	for _, arg := range args {
+		if flags.Lookup(arg.Name) != nil || (len(arg.Shorthand) > 0 && flags.ShorthandLookup(arg.Shorthand) != nil) {
+			fmt.Printf("⚠️ The runtime arg [%s] declared in '%s' conflicts with a built-in flag and is ignored...\n", arg.Name, manifest.FileName)
+			continue
+		}
+		switch arg.Type {
+		case manifest.ArgBool:
+			// 与 aurora.yaml 的校验一致, 默认值已校验过
+			defaultValue, _ := strconv.ParseBool(arg.Default)
+			flags.BoolP(arg.Name, arg.Shorthand, defaultValue, arg.Usage)
+		case manifest.ArgInt:
+			defaultValue, _ := strconv.Atoi(arg.Default)
+			flags.IntP(arg.Name, arg.Shorthand, defaultValue, arg.Usage)
+		case manifest.ArgStrings:
+			var defaultValue []string
+			if len(arg.Default) > 0 {
+				defaultValue = strings.Split(arg.Default, ",")
+			}
+			flags.StringSliceP(arg.Name, arg.Shorthand, defaultValue, arg.Usage)
+		default:
+			flags.StringP(arg.Name, arg.Shorthand, arg.Default, arg.Usage)
+		}
+	}
+}
+
+// 从命令中获取显式设置的 aurora.yaml 启动参数
+func getManifestRuntimeArgs(cmd *cobra.Command, args []manifest.Arg) []string {
+	var goArgs = make([]string, 0, len(args))
+	for _, arg := range args {
+		f := cmd.Flags().Lookup(arg.Name)
+		if f == nil || !f.Changed {
+			continue
+		}
+		if arg.Type == manifest.ArgStrings {
+			values, err := cmd.Flags().GetStringSlice(arg.Name)
+			if err != nil {
+				panic(err)
+			}
+			for _, v := range values {
+				goArgs = append(goArgs, fmt.Sprintf("--%s=%s", arg.Name, v))
+			}
+			continue
+		}
+		goArgs = append(goArgs, fmt.Sprintf("--%s=%s", arg.Name, f.Value.String()))
+	}
+	return goArgs
+}
+
+// 获取 "--" 之后需要透传给服务的参数
+func getPassThroughArgs(cmd *cobra.Command, args []string) []string {
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		return args[dash:]
+	}
+	return nil
+}
@@ -1,8 +1,17 @@
 package cmd
 
 import (
+	"fmt"
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
 	"github.com/samber/lo"
-	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/conf"
+	"github.com/stubborn-gaga-0805/aurora/pkg/manifest"
+	"golang.org/x/term"
+	"os"
+	"strings"
+	"sync"
 )
 
 type Env string
@@ -14,16 +23,73 @@ type Version string
 type ConfigFilePath string
 
 var (
-	supportsRuntimeEnvs = []Env{consts.EnvLocal, consts.EnvDev, consts.EnvTest, consts.EnvPre, consts.EnvProd}
-	debugEnvs           = []Env{consts.EnvLocal, consts.EnvDev, consts.EnvTest, consts.EnvPre, consts.EnvProd}
+	flagConfirmYes = flag{"yes", "y", false, "Do not prompt for confirmation in a protected environment"}
+
+	projectEnvsOnce sync.Once
+	projectEnvs     []manifest.Environment
 )
 
+// runtimeEnvs 项目支持的运行环境, 在 aurora.yaml 的 envs 中声明, 没有声明时为 local、dev、test、pre、prod
+func runtimeEnvs() []manifest.Environment {
+	projectEnvsOnce.Do(func() {
+		projectEnvs = manifest.DefaultEnvironments
+		wd, err := os.Getwd()
+		if err != nil {
+			return
+		}
+		// aurora.yaml 解析失败时由使用它的命令报错
+		if m, err := manifest.Load(wd); err == nil {
+			projectEnvs = m.Environments()
+		}
+	})
+	return projectEnvs
+}
+
+// supportsRuntimeEnvs 项目支持的运行环境
+func supportsRuntimeEnvs() []Env {
+	return lo.Map(runtimeEnvs(), func(env manifest.Environment, _ int) Env { return Env(env.Name) })
+}
+
+// defaultRuntimeEnv 默认的运行环境, 为声明的第一个环境
+func defaultRuntimeEnv() Env {
+	return Env(runtimeEnvs()[0].Name)
+}
+
+func (e Env) spec() (manifest.Environment, bool) {
+	return lo.Find(runtimeEnvs(), func(env manifest.Environment) bool { return env.Name == e.ToString() })
+}
+
 func (e Env) Check() bool {
-	return lo.Contains(supportsRuntimeEnvs, e)
+	_, ok := e.spec()
+	return ok
+}
+
+// checkRuntimeEnv 运行环境不是项目声明的环境时退出
+func checkRuntimeEnv(env Env) {
+	if !env.Check() {
+		fmt.Printf("🚫 Unsupported operating environment [%s], the supported environments are: %s\n", env, joinEnvs(supportsRuntimeEnvs()))
+		os.Exit(1)
+		return
+	}
 }
 
 func (e Env) IsDebug() bool {
-	return lo.Contains(debugEnvs, e)
+	spec, _ := e.spec()
+	return spec.Debug
+}
+
+// IsProtected 受保护的环境, 执行有破坏性的命令前需要确认
+func (e Env) IsProtected() bool {
+	spec, _ := e.spec()
+	return spec.Protected
+}
+
+// ConfigFile 环境的配置文件名 (位于 configs 目录), 未声明的环境为 config.<env>.yaml
+func (e Env) ConfigFile() string {
+	if spec, ok := e.spec(); ok {
+		return spec.ConfigFile()
+	}
+	return conf.ConfigFileName(e.ToString())
 }
 
 func (e Env) ToString() string {
@@ -45,3 +111,63 @@ func (e ConfigFilePath) ToString() string {
 func (e ConfigFilePath) UserDefined() bool {
 	return len(e.ToString()) > 0
 }
+
+// completeEnvs 补全 --env 参数, 候选项为项目声明的运行环境
+func completeEnvs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	envs := make([]string, 0, len(runtimeEnvs()))
+	for _, env := range runtimeEnvs() {
+		tags := []string{env.ConfigFile()}
+		if env.Debug {
+			tags = append(tags, "debug")
+		}
+		if env.Protected {
+			tags = append(tags, "protected")
+		}
+		envs = append(envs, fmt.Sprintf("%s\t%s", env.Name, strings.Join(tags, ", ")))
+	}
+	return envs, cobra.ShellCompDirectiveNoFileComp
+}
+
+func registerEnvCompletion(cmd *cobra.Command, flagName string) {
+	if err := cmd.RegisterFlagCompletionFunc(flagName, completeEnvs); err != nil {
+		panic(err)
+	}
+}
+
+// confirmProtectedEnv 在受保护的环境中执行有破坏性的命令前确认, 标准输入不是终端时需要使用 --yes
+func confirmProtectedEnv(env Env, action string, yes bool) bool {
+	if !env.IsProtected() || yes {
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Printf("🚫 [%s] is a protected environment, cannot prompt for confirmation, use --%s to %s\n", env, flagConfirmYes.name, action)
+		return false
+	}
+	var confirmed bool
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("[%s] is a protected environment, are you sure to %s ?", color.RedString(env.ToString()), action),
+		Default: false,
+	}
+	if err := survey.AskOne(prompt, &confirmed, survey.WithIcons(func(icons *survey.IconSet) {
+		icons.Question.Text = "⚠️"
+		icons.Question.Format = "red+b"
+	})); err != nil {
+		return false
+	}
+	return confirmed
+}
+
+func addConfirmYesFlag(cmd *cobra.Command, persistent bool) {
+	getFlags(cmd, persistent).BoolP(flagConfirmYes.name, flagConfirmYes.shortName, flagConfirmYes.defaultValue.(bool), flagConfirmYes.usage)
+}
+
+func getConfirmYes(cmd *cobra.Command) bool {
+	var (
+		yes bool
+		err error
+	)
+	if yes, err = cmd.Flags().GetBool(flagConfirmYes.name); err != nil {
+		panic(err)
+	}
+	return yes
+}
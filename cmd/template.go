@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/stubborn-gaga-0805/aurora/pkg/templates"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+type templateCmd struct {
+	*baseCmd
+}
+
+type templateListCmd struct {
+	*baseCmd
+}
+
+type templateAddCmd struct {
+	*baseCmd
+}
+
+type templateRemoveCmd struct {
+	*baseCmd
+}
+
+var (
+	flagTemplateBranch      = flag{"branch", "b", "", "The branch of the git repository"}
+	flagTemplateDescription = flag{"description", "d", "", "The description of the template"}
+	flagTemplateReplace     = flag{"force", "f", false, "Replace the template if the name already exists"}
+
+	// 内置的 prepare2go 模板
+	builtinTemplate = templates.Template{
+		Name:        templates.DefaultName,
+		Source:      consts.GoFrameRepoUrl,
+		Description: "The official prepare2go template",
+	}
+)
+
+func newTemplateCmd() *templateCmd {
+	tc := &templateCmd{newBaseCmd()}
+	tc.cmd = &cobra.Command{
+		Use:   "template",
+		Short: "Manage the project templates used by 'aurora create'",
+		Long:  "💡 Manage the project templates used by 'aurora create', eg: aurora template add my-tpl git@github.com:org/tpl.git",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Usage(); err != nil {
+				panic(err)
+			}
+		},
+	}
+	tc.addCommands(
+		newTemplateListCmd(),
+		newTemplateAddCmd(),
+		newTemplateRemoveCmd(),
+	)
+
+	return tc
+}
+
+func newTemplateListCmd() *templateListCmd {
+	tl := &templateListCmd{newBaseCmd()}
+	tl.cmd = &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the templates in the registry",
+		Long:    "💡 List the templates in the registry, eg: aurora template list",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			tl.run()
+		},
+	}
+
+	return tl
+}
+
+func (tl *templateListCmd) run() {
+	registry := mustLoadTemplateRegistry()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tSOURCE\tBRANCH\tDESCRIPTION")
+	for _, t := range registry.List() {
+		name := t.Name
+		if t.Builtin() {
+			name += " (built-in)"
+		}
+		branch := t.Branch
+		if len(branch) == 0 {
+			branch = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, templates.KindOf(t.Source), t.Source, branch, t.Description)
+	}
+	_ = w.Flush()
+	fmt.Printf("\n💡 Registry: %s\n", registry.Path())
+	return
+}
+
+func newTemplateAddCmd() *templateAddCmd {
+	ta := &templateAddCmd{newBaseCmd()}
+	ta.cmd = &cobra.Command{
+		Use:   "add <name> <git-url|local-dir|tar.gz>",
+		Short: "Add a template to the registry",
+		Long:  "💡 Add a template to the registry, eg: aurora template add internal git@git.example.com:go/prepare2go.git -b main",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			ta.run(cmd, args[0], args[1])
+		},
+	}
+	getFlags(ta.cmd, false).StringP(flagTemplateBranch.name, flagTemplateBranch.shortName, flagTemplateBranch.defaultValue.(string), flagTemplateBranch.usage)
+	getFlags(ta.cmd, false).StringP(flagTemplateDescription.name, flagTemplateDescription.shortName, flagTemplateDescription.defaultValue.(string), flagTemplateDescription.usage)
+	getFlags(ta.cmd, false).BoolP(flagTemplateReplace.name, flagTemplateReplace.shortName, flagTemplateReplace.defaultValue.(bool), flagTemplateReplace.usage)
+
+	return ta
+}
+
+func (ta *templateAddCmd) run(cmd *cobra.Command, name, source string) {
+	var (
+		registry   = mustLoadTemplateRegistry()
+		replace, _ = cmd.Flags().GetBool(flagTemplateReplace.name)
+		t          = templates.Template{
+			Name:        name,
+			Source:      templateSource(source),
+			Branch:      cmd.Flag(flagTemplateBranch.name).Value.String(),
+			Description: cmd.Flag(flagTemplateDescription.name).Value.String(),
+		}
+	)
+	if templates.KindOf(t.Source) == templates.KindGit && !isGitURL(t.Source) {
+		fmt.Printf("🚫 The template source [%s] is neither a git url nor an existing directory or .tar.gz archive...\n", source)
+		os.Exit(1)
+		return
+	}
+	if err := registry.Add(t, replace); err != nil {
+		fmt.Printf("🚫 Failed to add the template...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	if err := registry.Save(); err != nil {
+		fmt.Printf("🚫 Failed to save the template registry [%s]...[%v]\n", registry.Path(), err)
+		os.Exit(1)
+		return
+	}
+	fmt.Printf("✅ Template [%s] added, create a project with: %s\n", color.GreenString(name), color.GreenString("aurora create <project-name> --template %s", name))
+	return
+}
+
+func newTemplateRemoveCmd() *templateRemoveCmd {
+	tr := &templateRemoveCmd{newBaseCmd()}
+	tr.cmd = &cobra.Command{
+		Use:     "remove <name>",
+		Aliases: []string{"rm"},
+		Short:   "Remove a template from the registry",
+		Long:    "💡 Remove a template from the registry, eg: aurora template remove internal",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			tr.run(args[0])
+		},
+	}
+
+	return tr
+}
+
+func (tr *templateRemoveCmd) run(name string) {
+	registry := mustLoadTemplateRegistry()
+	if err := registry.Remove(name); err != nil {
+		fmt.Printf("🚫 Failed to remove the template...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	if err := registry.Save(); err != nil {
+		fmt.Printf("🚫 Failed to save the template registry [%s]...[%v]\n", registry.Path(), err)
+		os.Exit(1)
+		return
+	}
+	fmt.Printf("✅ Template [%s] removed\n", color.GreenString(name))
+	return
+}
+
+func loadTemplateRegistry() (*templates.Registry, error) {
+	path, err := templates.DefaultRegistryPath()
+	if err != nil {
+		return nil, err
+	}
+	return templates.LoadRegistry(path, builtinTemplate)
+}
+
+func mustLoadTemplateRegistry() *templates.Registry {
+	registry, err := loadTemplateRegistry()
+	if err != nil {
+		fmt.Printf("🚫 Failed to load the template registry...[%v]\n", err)
+		os.Exit(1)
+	}
+	return registry
+}
+
+// resolveTemplate 解析 --template: 注册表中的模板名、git仓库地址、本地目录或 .tar.gz 压缩包
+func resolveTemplate(ref string) (templates.Template, error) {
+	if len(ref) == 0 {
+		ref = templates.DefaultName
+	}
+	registry, err := loadTemplateRegistry()
+	if err != nil {
+		return templates.Template{}, fmt.Errorf("failed to load the template registry: %w", err)
+	}
+	if t, ok := registry.Get(ref); ok {
+		return t, nil
+	}
+	source := templateSource(ref)
+	if templates.KindOf(source) == templates.KindGit && !isGitURL(source) {
+		return templates.Template{}, fmt.Errorf("template [%s] is not found in the registry (%s), run 'aurora template list' to see the available templates", ref, registry.Path())
+	}
+	return templates.Template{Name: ref, Source: source}, nil
+}
+
+// templateSource 本地路径转换为绝对路径
+func templateSource(source string) string {
+	if isGitURL(source) {
+		return source
+	}
+	if absPath, err := filepath.Abs(expandHome(source)); err == nil {
+		if _, err = os.Stat(absPath); err == nil {
+			return absPath
+		}
+	}
+	return source
+}
+
+func isGitURL(source string) bool {
+	return strings.Contains(source, "://") || strings.HasPrefix(source, "git@") || strings.HasSuffix(source, ".git")
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/stubborn-gaga-0805/aurora/consts"
+	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/manifest"
+	"github.com/stubborn-gaga-0805/aurora/pkg/modrename"
+	"github.com/stubborn-gaga-0805/aurora/pkg/templates"
+	"github.com/stubborn-gaga-0805/aurora/pkg/upgrade"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+type upgradeCmd struct {
+	*baseCmd
+
+	template   manifest.Template
+	module     string
+	to         string
+	dryRun     bool
+	offline    bool
+	allowDirty bool
+}
+
+var (
+	flagUpgradeTo         = flag{"to", "", "", "The template branch, tag or commit to upgrade to (default: the branch recorded in 'aurora.yaml')"}
+	flagUpgradeDryRun     = flag{"dry-run", "", false, "Only list the files that would be changed"}
+	flagUpgradeOffline    = flag{"offline", "", false, "Upgrade from the local template cache without network access"}
+	flagUpgradeAllowDirty = flag{"allow-dirty", "", false, "Upgrade even if the git working tree has uncommitted changes"}
+)
+
+func newUpgradeCmd() *upgradeCmd {
+	uc := &upgradeCmd{baseCmd: newBaseCmd()}
+	uc.cmd = &cobra.Command{
+		Use:   "upgrade",
+		Short: "Merge the changes of the project template since the project was created into the project",
+		Long:  "💡 Three-way merge the template changes between the recorded template commit and the new one into the project, eg: aurora upgrade --dry-run",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			uc.initUpgradeRuntime(cmd)
+			uc.run()
+		},
+	}
+	getFlags(uc.cmd, false).String(flagUpgradeTo.name, flagUpgradeTo.defaultValue.(string), flagUpgradeTo.usage)
+	getFlags(uc.cmd, false).Bool(flagUpgradeDryRun.name, flagUpgradeDryRun.defaultValue.(bool), flagUpgradeDryRun.usage)
+	getFlags(uc.cmd, false).Bool(flagUpgradeOffline.name, flagUpgradeOffline.defaultValue.(bool), flagUpgradeOffline.usage)
+	getFlags(uc.cmd, false).Bool(flagUpgradeAllowDirty.name, flagUpgradeAllowDirty.defaultValue.(bool), flagUpgradeAllowDirty.usage)
+
+	return uc
+}
+
+func (uc *upgradeCmd) initUpgradeRuntime(cmd *cobra.Command) {
+	// 检查是否在项目目录下
+	if !uc.InProjectPath() {
+		fmt.Println("🚫 The 'main.go' file is not found in the current directory, please run it in the project root directory...")
+		os.Exit(1)
+		return
+	}
+	uc.to = cmd.Flag(flagUpgradeTo.name).Value.String()
+	uc.dryRun, _ = cmd.Flags().GetBool(flagUpgradeDryRun.name)
+	uc.offline, _ = cmd.Flags().GetBool(flagUpgradeOffline.name)
+	uc.allowDirty, _ = cmd.Flags().GetBool(flagUpgradeAllowDirty.name)
+
+	uc.template = uc.Manifest().Template
+	if len(uc.template.Source) == 0 || len(uc.template.Commit) == 0 {
+		fmt.Printf("🚫 The template commit is not recorded in '%s', only projects created from a git template by 'aurora create' can be upgraded...\n", manifest.FileName)
+		os.Exit(1)
+		return
+	}
+	if templates.KindOf(uc.template.Source) != templates.KindGit {
+		fmt.Printf("🚫 The template [%s] is not a git repository and can not be upgraded...\n", uc.template.Source)
+		os.Exit(1)
+		return
+	}
+	if len(uc.to) == 0 {
+		uc.to = uc.template.Branch
+	}
+	if len(uc.to) == 0 {
+		uc.to = "HEAD"
+	}
+	var err error
+	if uc.module, err = helpers.ModulePath(filepath.Join(uc.workingDir, "go.mod")); err != nil {
+		fmt.Printf("🚫 Failed to read the module path from 'go.mod'...[%v]\n", err)
+		os.Exit(1)
+		return
+	}
+	// 避免合并结果和未提交的修改混在一起
+	if !uc.dryRun && !uc.allowDirty {
+		if dirty, err := uc.isDirty(); err != nil {
+			fmt.Printf("⚠️ Failed to check the git working tree...[%v]\n", err)
+		} else if dirty {
+			fmt.Printf("🚫 The git working tree has uncommitted changes, commit or stash them first, or use --%s...\n", flagUpgradeAllowDirty.name)
+			os.Exit(1)
+			return
+		}
+	}
+	return
+}
+
+func (uc *upgradeCmd) run() {
+	cacheDir, err := templates.DefaultCacheDir()
+	if err != nil {
+		uc.stopped(err)
+		return
+	}
+	cache := templates.NewCache(cacheDir)
+	if !uc.offline {
+		fmt.Printf("🔄 Refreshing the template [%s]...\n", color.BlueString(uc.template.Source))
+		if err = cache.Refresh(uc.template.Source); err != nil {
+			if !cache.Cached(uc.template.Source) {
+				uc.stopped(err)
+				return
+			}
+			fmt.Printf("⚠️ Failed to refresh the template cache, the cached template is used...[%v]\n", err)
+		}
+	}
+	tmpDir, err := os.MkdirTemp("", "aurora-upgrade-")
+	if err != nil {
+		uc.stopped(err)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+	var (
+		baseDir = filepath.Join(tmpDir, "base")
+		newDir  = filepath.Join(tmpDir, "new")
+	)
+	baseCommit, err := cache.Export(uc.template.Source, uc.template.Commit, baseDir)
+	if err != nil {
+		uc.stopped(fmt.Errorf("the recorded template commit: %w", err))
+		return
+	}
+	newCommit, err := cache.Export(uc.template.Source, uc.to, newDir)
+	if err != nil {
+		uc.stopped(err)
+		return
+	}
+	if baseCommit == newCommit {
+		fmt.Printf("✅ The project is already up to date with the template [%s] at commit [%s]...\n", color.GreenString(uc.template.Name), color.GreenString(shortCommit(newCommit)))
+		return
+	}
+	fmt.Printf("🚀 Upgrading the template [%s] from [%s] to [%s]...\n", color.GreenString(uc.template.Name), color.BlueString(shortCommit(baseCommit)), color.GreenString(shortCommit(newCommit)))
+	// 使用创建项目时相同的变量和模块路径渲染两个版本的模板
+	for _, dir := range []string{baseDir, newDir} {
+		if err = uc.prepareTemplate(dir); err != nil {
+			uc.stopped(err)
+			return
+		}
+	}
+	changes, err := upgrade.Merge(baseDir, newDir, uc.workingDir, upgrade.Labels{
+		Project: "project",
+		Base:    "template " + shortCommit(baseCommit),
+		New:     "template " + shortCommit(newCommit),
+	}, uc.dryRun)
+	if err != nil {
+		uc.stopped(err)
+		return
+	}
+	changed, conflicts := uc.printChanges(changes)
+	if uc.dryRun {
+		fmt.Printf("\n💡 Dry run, %d file(s) would be changed, %d with conflicts...\n", changed, conflicts)
+		return
+	}
+	uc.template.Commit = newCommit
+	if err = manifest.SetTemplate(uc.workingDir, uc.template); err != nil {
+		uc.stopped(err)
+		return
+	}
+	if conflicts > 0 {
+		fmt.Printf("\n⚠️ %d file(s) have conflicts, resolve the conflict markers (<<<<<<< / >>>>>>>) and run %s...\n", conflicts, color.GreenString("go build ./..."))
+		return
+	}
+	fmt.Printf("\n✅ Upgraded to commit [%s], %d file(s) changed, review them with %s...\n", color.GreenString(shortCommit(newCommit)), changed, color.GreenString("git diff"))
+	return
+}
+
+// prepareTemplate 按创建项目时的方式处理导出的模板: 应用 aurora-template.yaml 并修改模块路径
+func (uc *upgradeCmd) prepareTemplate(dir string) error {
+	tplManifest, err := templates.LoadManifest(dir)
+	if err != nil {
+		return err
+	}
+	if tplManifest != nil {
+		// 之前创建的项目没有记录项目名称, 使用项目目录的名称
+		projectName := uc.template.ProjectName
+		if len(projectName) == 0 {
+			projectName = filepath.Base(uc.workingDir)
+		}
+		values, err := tplManifest.Values(map[string]interface{}{
+			"ProjectName": projectName,
+			"Module":      uc.module,
+		}, uc.template.Variables)
+		if err != nil {
+			return err
+		}
+		if _, _, err = tplManifest.Apply(dir, values); err != nil {
+			return err
+		}
+	}
+	templateModule, err := helpers.ModulePath(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		templateModule = consts.GoFrameModule
+	}
+	if templateModule == uc.module {
+		return nil
+	}
+	_, err = modrename.Rename(dir, templateModule, uc.module, false)
+	return err
+}
+
+// printChanges 打印受影响的文件, 返回被修改和有冲突的文件数量
+func (uc *upgradeCmd) printChanges(changes []upgrade.Change) (changed, conflicts int) {
+	if len(changes) == 0 {
+		fmt.Println("💡 No files are affected...")
+		return 0, 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nACTION\tFILE\tNOTE")
+	for _, change := range changes {
+		action := string(change.Action)
+		if change.Action != upgrade.ActionSkip {
+			changed++
+		}
+		switch change.Action {
+		case upgrade.ActionConflict:
+			conflicts++
+			action = color.RedString(action)
+		case upgrade.ActionSkip:
+			action = color.YellowString(action)
+		default:
+			action = color.GreenString(action)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", action, change.Path, color.HiBlackString(change.Reason))
+	}
+	_ = w.Flush()
+	return changed, conflicts
+}
+
+// isDirty git工作区是否有未提交的修改, 不是git仓库时返回 false
+func (uc *upgradeCmd) isDirty() (bool, error) {
+	if _, err := os.Stat(filepath.Join(uc.workingDir, ".git")); os.IsNotExist(err) {
+		return false, nil
+	}
+	cmd := exec.Command("git", "status", "--porcelain")
+	cmd.Dir = uc.workingDir
+	output, err := cmd.Output()
+	if err != nil {
+		return false, err
+	}
+	return len(strings.TrimSpace(string(output))) > 0, nil
+}
+
+func (uc *upgradeCmd) stopped(err error) {
+	fmt.Printf("🚫[Command: %s] execution failed...[%v]\n", uc.cmd.Use, err)
+	os.Exit(1)
+	return
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -11,17 +11,20 @@ const (
 	Replica DBResolverType = "replica"
 )
 
-var supportedDrivers = []DBDriver{MySQL}
+var (
+	supportedDrivers       = []DBDriver{MySQL}
+	supportedResolverTypes = []DBResolverType{Source, Replica}
+)
 
 type DBDriver string
 type DBResolverType string
 
-// DB 数据库配置结构体
+// DB 数据库配置结构体, validate:"required" 的字段为必填项 (aurora config validate)
 type DB struct {
-	Driver          DBDriver       `json:"driver" yaml:"driver"`
+	Driver          DBDriver       `json:"driver" yaml:"driver" validate:"required"`
 	Type            DBResolverType `json:"-" yaml:"-"`
-	Addr            string         `json:"addr" yaml:"addr"`
-	Database        string         `json:"database" yaml:"database"`
+	Addr            string         `json:"addr" yaml:"addr" validate:"required"`
+	Database        string         `json:"database" yaml:"database" validate:"required"`
 	Username        string         `json:"username" yaml:"username"`
 	Password        string         `json:"password" yaml:"password"`
 	Options         string         `json:"options" yaml:"options"`
@@ -70,8 +73,8 @@ type DBConnPool struct {
 // DBResolver 数据库主从配置
 type DBResolver struct {
 	Driver          DBDriver       `json:"-" yaml:"-"`
-	Type            DBResolverType `json:"type" yaml:"type"`
-	Addr            string         `json:"addr" yaml:"addr"`
+	Type            DBResolverType `json:"type" yaml:"type" validate:"required"`
+	Addr            string         `json:"addr" yaml:"addr" validate:"required"`
 	Database        string         `json:"database" yaml:"database"`
 	Username        string         `json:"username" yaml:"username"`
 	Password        string         `json:"password" yaml:"password"`
@@ -96,6 +99,21 @@ func (d DBDriver) IsSupported() bool {
 	return lo.Contains(supportedDrivers, d)
 }
 
+// Enum 支持的驱动
+func (d DBDriver) Enum() []string {
+	return lo.Map(supportedDrivers, func(item DBDriver, _ int) string { return item.ToString() })
+}
+
+// IsSupported 检查是否支持的主从类型
+func (r DBResolverType) IsSupported() bool {
+	return lo.Contains(supportedResolverTypes, r)
+}
+
+// Enum 支持的主从类型
+func (r DBResolverType) Enum() []string {
+	return lo.Map(supportedResolverTypes, func(item DBResolverType, _ int) string { return item.ToString() })
+}
+
 func (db DB) Equals(other DB) bool {
 	return db.Addr == other.Addr &&
 		db.Driver == other.Driver &&
@@ -0,0 +1,360 @@
+package conf
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EnvPrefix 覆盖配置项的环境变量前缀, 如: AURORA_DATA_DB_ADDR -> data.db.addr
+	EnvPrefix = "AURORA_"
+	// BaseConfigFile 所有环境共用的配置文件, 与 config.<env>.yaml 深度合并
+	BaseConfigFile = "config.yaml"
+)
+
+// 配置项来源的类型, 优先级由低到高
+const (
+	OriginFile = "file"
+	OriginEnv  = "env"
+	OriginSet  = "set"
+)
+
+// Origin 配置项的来源
+type Origin struct {
+	Kind string `json:"kind"`
+	// Name 配置文件路径、环境变量名或 --set 的参数
+	Name string `json:"name"`
+}
+
+func (o Origin) String() string {
+	switch o.Kind {
+	case OriginEnv:
+		return "env " + o.Name
+	case OriginSet:
+		return "--set " + o.Name
+	}
+	return o.Name
+}
+
+// LoadOptions 加载配置的参数
+type LoadOptions struct {
+	// Dir 配置文件所在目录, 如: ./configs
+	Dir string
+	Env string
+	// EnvFile 环境的配置文件名, 默认: config.<env>.yaml
+	EnvFile string
+	// File 指定配置文件时只加载该文件, 不再叠加 config.yaml 和 config.<env>.yaml
+	File string
+	// Environ 用于覆盖配置项的环境变量 (KEY=VALUE), 只使用 EnvPrefix 开头的变量
+	Environ []string
+	// Sets 覆盖配置项的 key=value, 如: data.db.addr=127.0.0.1:3306
+	Sets []string
+}
+
+// Layered 分层合并后的配置
+type Layered struct {
+	// Files 按顺序合并的配置文件
+	Files []string
+
+	root    *yaml.Node
+	origins map[string]Origin
+	// overrides 环境变量和 --set 覆盖的配置项数量
+	overrides int
+}
+
+// ConfigFileName 环境对应的配置文件名
+func ConfigFileName(env string) string {
+	return fmt.Sprintf("config.%s.yaml", env)
+}
+
+// ConfigFiles 环境需要合并的配置文件 (只返回存在的文件): config.yaml 以及环境的配置文件 envFile
+func ConfigFiles(dir, envFile string) []string {
+	files := make([]string, 0, 2)
+	for _, name := range []string{BaseConfigFile, envFile} {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// LoadLayered 依次合并 config.yaml、环境的配置文件 (默认: config.<env>.yaml)、环境变量 (AURORA_*) 以及 --set 的配置,
+// 后面的覆盖前面的: 映射深度合并, 其他值 (包括列表) 整体替换. 配置项不区分大小写, 与viper一致
+func LoadLayered(opts LoadOptions) (*Layered, error) {
+	l := &Layered{
+		root:    &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+		origins: make(map[string]Origin),
+	}
+	if len(opts.EnvFile) == 0 {
+		opts.EnvFile = ConfigFileName(opts.Env)
+	}
+	if len(opts.File) > 0 {
+		l.Files = []string{opts.File}
+	} else {
+		l.Files = ConfigFiles(opts.Dir, opts.EnvFile)
+	}
+	if len(l.Files) == 0 {
+		return nil, fmt.Errorf("neither %s nor %s is found in [%s]", BaseConfigFile, opts.EnvFile, opts.Dir)
+	}
+	for _, file := range l.Files {
+		node, err := ParseFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if node != nil && len(node.Content) > 0 {
+			l.record(node, nil, Origin{Kind: OriginFile, Name: file})
+			MergeNodes(l.root, node)
+		}
+	}
+	for _, kv := range opts.Environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, EnvPrefix) || len(key) == len(EnvPrefix) {
+			continue
+		}
+		segments := strings.Split(key[len(EnvPrefix):], "_")
+		if indexEmpty(segments) >= 0 {
+			continue
+		}
+		l.set(envPath(l.root, segments), value, Origin{Kind: OriginEnv, Name: key})
+	}
+	for _, kv := range opts.Sets {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || len(strings.TrimSpace(key)) == 0 {
+			return nil, fmt.Errorf("invalid --set %q, expected key=value", kv)
+		}
+		segments := strings.Split(strings.TrimSpace(key), ".")
+		if indexEmpty(segments) >= 0 {
+			return nil, fmt.Errorf("invalid --set %q, expected key=value", kv)
+		}
+		l.set(setPath(l.root, segments), value, Origin{Kind: OriginSet, Name: kv})
+	}
+	return l, nil
+}
+
+// ParseFile 解析yaml配置文件, 返回根节点, 文件为空时返回nil
+func ParseFile(path string) (*yaml.Node, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err = yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: the config must be a mapping", path)
+	}
+	return doc.Content[0], nil
+}
+
+// MergeNodes 将 src 深度合并到 dst: 两边都是映射时递归合并, 否则 src 的值替换 dst 的值
+func MergeNodes(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		j := keyIndex(dst, key.Value)
+		if j < 0 {
+			dst.Content = append(dst.Content, key, value)
+			continue
+		}
+		if dst.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			MergeNodes(dst.Content[j+1], value)
+			continue
+		}
+		dst.Content[j+1] = value
+	}
+}
+
+// Node 合并后配置的根节点
+func (l *Layered) Node() *yaml.Node {
+	return l.root
+}
+
+// Overridden 是否有多个配置来源 (多个配置文件、环境变量或 --set)
+func (l *Layered) Overridden() bool {
+	return len(l.Files) > 1 || l.overrides > 0
+}
+
+// Origin 配置项的来源, key 为 "." 分隔的路径, 如: data.db.addr
+func (l *Layered) Origin(key string) (Origin, bool) {
+	origin, ok := l.origins[strings.ToLower(key)]
+	return origin, ok
+}
+
+// Settings 合并后的配置
+func (l *Layered) Settings() (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	if err := l.root.Decode(&settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Viper 使用合并后的配置创建viper实例
+func (l *Layered) Viper() (*viper.Viper, error) {
+	settings, err := l.Settings()
+	if err != nil {
+		return nil, err
+	}
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err = v.MergeConfigMap(settings); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Unmarshal 将合并后的配置解析到结构体
+func (l *Layered) Unmarshal(out interface{}) error {
+	v, err := l.Viper()
+	if err != nil {
+		return err
+	}
+	return v.Unmarshal(out)
+}
+
+// YAML 合并后的配置文件内容
+func (l *Layered) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(l.root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// record 记录节点中每个配置项的来源, 覆盖之前的来源
+func (l *Layered) record(node *yaml.Node, path []string, origin Origin) {
+	if node.Kind == yaml.MappingNode && len(node.Content) > 0 {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			l.record(node.Content[i+1], append(append([]string{}, path...), node.Content[i].Value), origin)
+		}
+		return
+	}
+	key := strings.ToLower(strings.Join(path, "."))
+	for k := range l.origins {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(key, k+".") {
+			delete(l.origins, k)
+		}
+	}
+	l.origins[key] = origin
+}
+
+// set 覆盖配置项, 值的类型与原来的值保持一致 (如果可以转换)
+func (l *Layered) set(path []string, value string, origin Origin) {
+	node := l.root
+	for i, key := range path {
+		j := keyIndex(node, key)
+		if i == len(path)-1 {
+			scalar := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+			if j >= 0 {
+				scalar.Tag = scalarTag(node.Content[j+1], value)
+				node.Content[j+1] = scalar
+			} else {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, scalar)
+			}
+			break
+		}
+		if j < 0 {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+			j = len(node.Content) - 2
+		} else if node.Content[j+1].Kind != yaml.MappingNode {
+			node.Content[j+1] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		node = node.Content[j+1]
+	}
+	l.record(&yaml.Node{Kind: yaml.ScalarNode}, path, origin)
+	l.overrides++
+}
+
+// scalarTag 覆盖的值能转换为原来的类型时保留原来的类型, 否则作为字符串
+func scalarTag(old *yaml.Node, value string) string {
+	if old.Kind != yaml.ScalarNode {
+		return "!!str"
+	}
+	switch old.Tag {
+	case "!!int":
+		if _, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return old.Tag
+		}
+	case "!!float":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return old.Tag
+		}
+	case "!!bool":
+		if _, err := strconv.ParseBool(value); err == nil {
+			return old.Tag
+		}
+	}
+	return "!!str"
+}
+
+// envPath 环境变量对应的配置项路径, 优先匹配已有的配置项 (配置项中可能包含 "_"), 不存在的配置项使用小写
+func envPath(node *yaml.Node, segments []string) []string {
+	if len(segments) == 0 {
+		return nil
+	}
+	if node != nil && node.Kind == yaml.MappingNode {
+		for n := len(segments); n > 0; n-- {
+			if j := keyIndex(node, strings.Join(segments[:n], "_")); j >= 0 {
+				return append([]string{node.Content[j].Value}, envPath(node.Content[j+1], segments[n:])...)
+			}
+		}
+	}
+	path := make([]string, len(segments))
+	for i, segment := range segments {
+		path[i] = strings.ToLower(segment)
+	}
+	return path
+}
+
+// setPath --set 对应的配置项路径, 已有的配置项使用配置文件中的写法
+func setPath(node *yaml.Node, segments []string) []string {
+	path := make([]string, len(segments))
+	for i, segment := range segments {
+		path[i] = segment
+		if node == nil || node.Kind != yaml.MappingNode {
+			node = nil
+			continue
+		}
+		j := keyIndex(node, segment)
+		if j < 0 {
+			node = nil
+			continue
+		}
+		path[i] = node.Content[j].Value
+		node = node.Content[j+1]
+	}
+	return path
+}
+
+func keyIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexEmpty(segments []string) int {
+	for i, segment := range segments {
+		if len(segment) == 0 {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,422 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	testBaseConfig = `env:
+  appName: demo
+  debug: true
+data:
+  db:
+    addr: 127.0.0.1:3306
+    maxIdleConn: 10
+    max_open: 20
+    timeout: 1.5
+  hosts: [a, b]
+`
+	testEnvConfig = `env:
+  debug: false
+data:
+  db:
+    addr: 10.0.0.1:3306
+  hosts: [c]
+`
+)
+
+func writeConfigs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// lookup 按 "." 分隔的路径 (不区分大小写) 查找合并后的配置项
+func lookup(t *testing.T, l *Layered, key string) *yaml.Node {
+	t.Helper()
+	node := l.Node()
+	for _, segment := range strings.Split(key, ".") {
+		i := keyIndex(node, segment)
+		if i < 0 {
+			t.Fatalf("%s is not found in the merged config", key)
+		}
+		node = node.Content[i+1]
+	}
+	return node
+}
+
+func TestLoadLayeredFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		opts  LoadOptions
+		want  map[string]string
+		// wantFiles 合并的配置文件名
+		wantFiles []string
+		// overridden 有多个配置文件时需要合并后传递给服务进程
+		overridden bool
+		wantErr    bool
+	}{
+		{
+			name:       "base and env are deep merged",
+			files:      map[string]string{BaseConfigFile: testBaseConfig, "config.prod.yaml": testEnvConfig},
+			opts:       LoadOptions{Env: "prod"},
+			want:       map[string]string{"env.appName": "demo", "env.debug": "false", "data.db.addr": "10.0.0.1:3306", "data.db.maxIdleConn": "10"},
+			wantFiles:  []string{BaseConfigFile, "config.prod.yaml"},
+			overridden: true,
+		},
+		{
+			name:      "only the base config",
+			files:     map[string]string{BaseConfigFile: testBaseConfig},
+			opts:      LoadOptions{Env: "prod"},
+			want:      map[string]string{"data.db.addr": "127.0.0.1:3306"},
+			wantFiles: []string{BaseConfigFile},
+		},
+		{
+			name:      "only the env config",
+			files:     map[string]string{"config.prod.yaml": testEnvConfig},
+			opts:      LoadOptions{Env: "prod"},
+			want:      map[string]string{"data.db.addr": "10.0.0.1:3306"},
+			wantFiles: []string{"config.prod.yaml"},
+		},
+		{
+			name:       "custom env config file",
+			files:      map[string]string{BaseConfigFile: testBaseConfig, "prod.yaml": testEnvConfig, "config.prod.yaml": "env:\n  appName: ignored\n"},
+			opts:       LoadOptions{Env: "prod", EnvFile: "prod.yaml"},
+			want:       map[string]string{"env.appName": "demo", "data.db.addr": "10.0.0.1:3306"},
+			wantFiles:  []string{BaseConfigFile, "prod.yaml"},
+			overridden: true,
+		},
+		{
+			name:       "keys are merged case-insensitively",
+			files:      map[string]string{BaseConfigFile: testBaseConfig, "config.prod.yaml": "DATA:\n  DB:\n    MaxIdleConn: 5\n"},
+			opts:       LoadOptions{Env: "prod"},
+			want:       map[string]string{"data.db.maxIdleConn": "5", "data.db.addr": "127.0.0.1:3306"},
+			wantFiles:  []string{BaseConfigFile, "config.prod.yaml"},
+			overridden: true,
+		},
+		{
+			name:       "a scalar replaces a mapping",
+			files:      map[string]string{BaseConfigFile: testBaseConfig, "config.prod.yaml": "data:\n  db: disabled\n"},
+			opts:       LoadOptions{Env: "prod"},
+			want:       map[string]string{"data.db": "disabled"},
+			wantFiles:  []string{BaseConfigFile, "config.prod.yaml"},
+			overridden: true,
+		},
+		{
+			name:      "the file option skips the layered files",
+			files:     map[string]string{BaseConfigFile: testBaseConfig, "custom.yaml": testEnvConfig},
+			opts:      LoadOptions{Env: "prod", File: "custom.yaml"},
+			want:      map[string]string{"data.db.addr": "10.0.0.1:3306"},
+			wantFiles: []string{"custom.yaml"},
+		},
+		{
+			name:    "no config file",
+			files:   map[string]string{"config.dev.yaml": testEnvConfig},
+			opts:    LoadOptions{Env: "prod"},
+			wantErr: true,
+		},
+		{
+			name:    "the config must be a mapping",
+			files:   map[string]string{BaseConfigFile: "- a\n- b\n"},
+			opts:    LoadOptions{Env: "prod"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigs(t, tt.files)
+			tt.opts.Dir = dir
+			if len(tt.opts.File) > 0 {
+				tt.opts.File = filepath.Join(dir, tt.opts.File)
+			}
+			l, err := LoadLayered(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadLayered() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			files := make([]string, len(l.Files))
+			for i, file := range l.Files {
+				files[i] = filepath.Base(file)
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("Files = %v, want %v", files, tt.wantFiles)
+			}
+			if l.Overridden() != tt.overridden {
+				t.Errorf("Overridden() = %v, want %v", l.Overridden(), tt.overridden)
+			}
+			for key, want := range tt.want {
+				if got := lookup(t, l, key).Value; got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadLayeredListsAreReplaced(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{BaseConfigFile: testBaseConfig, "config.prod.yaml": testEnvConfig})
+	l, err := LoadLayered(LoadOptions{Dir: dir, Env: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts []string
+	if err = lookup(t, l, "data.hosts").Decode(&hosts); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hosts, []string{"c"}) {
+		t.Errorf("data.hosts = %v, want [c]", hosts)
+	}
+}
+
+func TestLoadLayeredOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		sets    []string
+		key     string
+		value   string
+		tag     string
+		origin  Origin
+	}{
+		{
+			name:    "env var",
+			environ: []string{"AURORA_DATA_DB_ADDR=192.168.0.1:3306"},
+			key:     "data.db.addr", value: "192.168.0.1:3306", tag: "!!str",
+			origin: Origin{Kind: OriginEnv, Name: "AURORA_DATA_DB_ADDR"},
+		},
+		{
+			name:    "env var matches an existing camelCase key",
+			environ: []string{"AURORA_DATA_DB_MAXIDLECONN=30"},
+			key:     "data.db.maxIdleConn", value: "30", tag: "!!int",
+			origin: Origin{Kind: OriginEnv, Name: "AURORA_DATA_DB_MAXIDLECONN"},
+		},
+		{
+			name:    "env var matches an existing key containing _",
+			environ: []string{"AURORA_DATA_DB_MAX_OPEN=50"},
+			key:     "data.db.max_open", value: "50", tag: "!!int",
+			origin: Origin{Kind: OriginEnv, Name: "AURORA_DATA_DB_MAX_OPEN"},
+		},
+		{
+			name:    "env var for a new key is lowercased",
+			environ: []string{"AURORA_DATA_MQ_ADDR=127.0.0.1:5672"},
+			key:     "data.mq.addr", value: "127.0.0.1:5672", tag: "!!str",
+			origin: Origin{Kind: OriginEnv, Name: "AURORA_DATA_MQ_ADDR"},
+		},
+		{
+			name:    "a value that is not an int becomes a string",
+			environ: []string{"AURORA_DATA_DB_MAXIDLECONN=many"},
+			key:     "data.db.maxIdleConn", value: "many", tag: "!!str",
+			origin: Origin{Kind: OriginEnv, Name: "AURORA_DATA_DB_MAXIDLECONN"},
+		},
+		{
+			name:    "bool keeps its type",
+			environ: []string{"AURORA_ENV_DEBUG=false"},
+			key:     "env.debug", value: "false", tag: "!!bool",
+			origin: Origin{Kind: OriginEnv, Name: "AURORA_ENV_DEBUG"},
+		},
+		{
+			name:    "float keeps its type",
+			environ: []string{"AURORA_DATA_DB_TIMEOUT=2.5"},
+			key:     "data.db.timeout", value: "2.5", tag: "!!float",
+			origin: Origin{Kind: OriginEnv, Name: "AURORA_DATA_DB_TIMEOUT"},
+		},
+		{
+			name: "env vars without the prefix or with empty segments are ignored",
+			environ: []string{
+				"DATA_DB_ADDR=ignored", "AURORA_=ignored", "AURORA_DATA__ADDR=ignored", "AURORA_DATA_DB_ADDR_=ignored", "AURORA_DATA_DB",
+			},
+			key: "data.db.addr", value: "127.0.0.1:3306", tag: "!!str",
+			origin: Origin{Kind: OriginFile, Name: BaseConfigFile},
+		},
+		{
+			name: "--set",
+			sets: []string{"data.db.addr=172.16.0.1:3306"},
+			key:  "data.db.addr", value: "172.16.0.1:3306", tag: "!!str",
+			origin: Origin{Kind: OriginSet, Name: "data.db.addr=172.16.0.1:3306"},
+		},
+		{
+			name: "--set uses the case of the existing key",
+			sets: []string{"DATA.DB.MAXIDLECONN=40"},
+			key:  "data.db.maxIdleConn", value: "40", tag: "!!int",
+			origin: Origin{Kind: OriginSet, Name: "DATA.DB.MAXIDLECONN=40"},
+		},
+		{
+			name:    "--set overrides env vars",
+			environ: []string{"AURORA_DATA_DB_ADDR=192.168.0.1:3306"},
+			sets:    []string{"data.db.addr=172.16.0.1:3306"},
+			key:     "data.db.addr", value: "172.16.0.1:3306", tag: "!!str",
+			origin: Origin{Kind: OriginSet, Name: "data.db.addr=172.16.0.1:3306"},
+		},
+		{
+			name: "--set replaces a mapping",
+			sets: []string{"data.db=none"},
+			key:  "data.db", value: "none", tag: "!!str",
+			origin: Origin{Kind: OriginSet, Name: "data.db=none"},
+		},
+		{
+			name: "--set value may contain =",
+			sets: []string{"data.db.dsn=user:pw@tcp(host)/db?a=b"},
+			key:  "data.db.dsn", value: "user:pw@tcp(host)/db?a=b", tag: "!!str",
+			origin: Origin{Kind: OriginSet, Name: "data.db.dsn=user:pw@tcp(host)/db?a=b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigs(t, map[string]string{BaseConfigFile: testBaseConfig})
+			l, err := LoadLayered(LoadOptions{Dir: dir, Env: "prod", Environ: tt.environ, Sets: tt.sets})
+			if err != nil {
+				t.Fatal(err)
+			}
+			node := lookup(t, l, tt.key)
+			if node.Value != tt.value || node.Tag != tt.tag {
+				t.Errorf("%s = %q (%s), want %q (%s)", tt.key, node.Value, node.Tag, tt.value, tt.tag)
+			}
+			origin, ok := l.Origin(tt.key)
+			if tt.origin.Kind == OriginFile {
+				tt.origin.Name = filepath.Join(dir, tt.origin.Name)
+			}
+			if !ok || origin != tt.origin {
+				t.Errorf("Origin(%s) = %v, %v, want %v", tt.key, origin, ok, tt.origin)
+			}
+			if want := len(tt.environ) > 0 && tt.origin.Kind != OriginFile || len(tt.sets) > 0; l.Overridden() != want {
+				t.Errorf("Overridden() = %v, want %v", l.Overridden(), want)
+			}
+		})
+	}
+}
+
+func TestLoadLayeredInvalidSet(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{BaseConfigFile: testBaseConfig})
+	for _, set := range []string{"data.db.addr", "=value", " =value", "data..addr=x", ".data=x"} {
+		if _, err := LoadLayered(LoadOptions{Dir: dir, Sets: []string{set}}); err == nil {
+			t.Errorf("LoadLayered() with --set %q, want an error", set)
+		}
+	}
+}
+
+func TestLoadLayeredOrigins(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{BaseConfigFile: testBaseConfig, "config.prod.yaml": testEnvConfig})
+	l, err := LoadLayered(LoadOptions{Dir: dir, Env: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, file := range map[string]string{
+		"env.appName":         BaseConfigFile,
+		"env.debug":           "config.prod.yaml",
+		"DATA.DB.ADDR":        "config.prod.yaml",
+		"data.db.maxIdleConn": BaseConfigFile,
+		"data.hosts":          "config.prod.yaml",
+	} {
+		origin, ok := l.Origin(key)
+		if want := (Origin{Kind: OriginFile, Name: filepath.Join(dir, file)}); !ok || origin != want {
+			t.Errorf("Origin(%s) = %v, %v, want %v", key, origin, ok, want)
+		}
+	}
+	if !l.Overridden() {
+		t.Errorf("Overridden() = false with two config files")
+	}
+}
+
+func TestLayeredUnmarshal(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{BaseConfigFile: testBaseConfig, "config.prod.yaml": testEnvConfig})
+	l, err := LoadLayered(LoadOptions{Dir: dir, Env: "prod", Environ: []string{"AURORA_DATA_DB_MAXIDLECONN=30"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Env struct {
+			AppName string
+			Debug   bool
+		}
+		Data struct {
+			DB struct {
+				Addr        string
+				MaxIdleConn int
+			}
+		}
+	}
+	if err = l.Unmarshal(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Env.AppName != "demo" || out.Env.Debug || out.Data.DB.Addr != "10.0.0.1:3306" || out.Data.DB.MaxIdleConn != 30 {
+		t.Errorf("Unmarshal() = %+v", out)
+	}
+}
+
+func TestMergeNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst, src string
+		want     string
+	}{
+		{
+			name: "nested mappings are merged",
+			dst:  "a:\n  b: 1\n  c: 2\n",
+			src:  "a:\n  c: 3\n  d: 4\n",
+			want: "a:\n  b: 1\n  c: 3\n  d: 4\n",
+		},
+		{
+			name: "keys are matched case-insensitively and keep the dst case",
+			dst:  "Data:\n  DB: 1\n",
+			src:  "data:\n  db: 2\n",
+			want: "Data:\n  DB: 2\n",
+		},
+		{
+			name: "lists are replaced",
+			dst:  "a: [1, 2]\n",
+			src:  "a: [3]\n",
+			want: "a: [3]\n",
+		},
+		{
+			name: "a mapping replaces a scalar",
+			dst:  "a: 1\n",
+			src:  "a:\n  b: 2\n",
+			want: "a:\n  b: 2\n",
+		},
+		{
+			name: "null replaces a value",
+			dst:  "a:\n  b: 1\n",
+			src:  "a: ~\n",
+			want: "a: ~\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst, src, want := parseNode(t, tt.dst), parseNode(t, tt.src), parseNode(t, tt.want)
+			MergeNodes(dst, src)
+			var got, expected interface{}
+			if err := dst.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if err := want.Decode(&expected); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("MergeNodes() = %v, want %v", got, expected)
+			}
+		})
+	}
+}
+
+func parseNode(t *testing.T, content string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc.Content[0]
+}
@@ -1,8 +1,10 @@
 package helpers
 
 import (
+	"fmt"
 	"github.com/cheggaaa/pb/v3"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -21,3 +23,64 @@ func NewProgressBar(count int, msg string) (bar *pb.ProgressBar) {
 
 	return bar
 }
+
+// ModulePath 读取 go.mod 中的模块名
+func ModulePath(goModPath string) (string, error) {
+	return goModDirective(goModPath, "module")
+}
+
+// GoVersion 读取 go.mod 中的 go 版本, 如: 1.20
+func GoVersion(goModPath string) (string, error) {
+	return goModDirective(goModPath, "go")
+}
+
+func goModDirective(goModPath, directive string) (string, error) {
+	content, err := os.ReadFile(goModPath)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == directive {
+			return strings.Trim(fields[1], "\"`"), nil
+		}
+	}
+	return "", fmt.Errorf("no %s directive found in %s", directive, goModPath)
+}
+
+// GoModRequires 读取 go.mod 中 require 的模块及版本
+func GoModRequires(goModPath string) (map[string]string, error) {
+	content, err := os.ReadFile(goModPath)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		requires = make(map[string]string)
+		inBlock  bool
+	)
+	for _, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+			continue
+		case fields[0] == "require" && len(fields) == 3:
+			fields = fields[1:]
+		case !inBlock || len(fields) != 2:
+			continue
+		}
+		requires[strings.Trim(fields[0], "\"`")] = fields[1]
+	}
+	return requires, nil
+}
@@ -0,0 +1,216 @@
+package buildcache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Suffix 哈希文件的后缀, 哈希文件与二进制文件放在同一目录下
+const Suffix = ".hash"
+
+// embedDirective 嵌入文件的编译指令
+const embedDirective = "//go:embed"
+
+// Sum 计算 go.mod、go.sum、所有 .go 源码文件、//go:embed 嵌入的文件以及编译参数的哈希
+func Sum(dir string, buildArgs []string) (string, error) {
+	h := sha256.New()
+	for _, arg := range buildArgs {
+		_, _ = io.WriteString(h, arg)
+		_, _ = h.Write([]byte{0})
+	}
+	files := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && skipDir(dir, path, name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isSource(name) {
+			return nil
+		}
+		files[path] = true
+		if !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		embedded, err := embedFiles(path)
+		if err != nil {
+			return err
+		}
+		for _, file := range embedded {
+			files[file] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return "", err
+		}
+		if err = hashFile(h, filepath.ToSlash(rel), path); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Fresh 二进制文件存在, 且记录的哈希与当前哈希一致
+func Fresh(bin, sum string) bool {
+	if _, err := os.Stat(bin); err != nil {
+		return false
+	}
+	stored, err := os.ReadFile(bin + Suffix)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(stored)) == sum
+}
+
+// Store 记录二进制文件对应的哈希
+func Store(bin, sum string) error {
+	return os.WriteFile(bin+Suffix, []byte(sum+"\n"), 0644)
+}
+
+// skipDir 不参与哈希计算的目录: 项目根目录下的 bin 以及 go 命令忽略的目录
+func skipDir(root, path, name string) bool {
+	if name == "bin" && filepath.Dir(path) == root {
+		return true
+	}
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata"
+}
+
+func isSource(name string) bool {
+	switch name {
+	case "go.mod", "go.sum", "go.work", "go.work.sum":
+		return true
+	}
+	return strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+}
+
+// embedFiles 源码文件中 //go:embed 指令嵌入的文件, 与 go build 一样目录中以 . 或 _ 开头的文件只有 all: 前缀时嵌入
+func embedFiles(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(content, []byte(embedDirective)) {
+		return nil, nil
+	}
+	var (
+		files   []string
+		pkgDir  = filepath.Dir(path)
+		scanner = bufio.NewScanner(bytes.NewReader(content))
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, embedDirective+" ") && !strings.HasPrefix(line, embedDirective+"\t") {
+			continue
+		}
+		for _, pattern := range embedPatterns(strings.TrimPrefix(line, embedDirective)) {
+			all := strings.HasPrefix(pattern, "all:")
+			matches, err := filepath.Glob(filepath.Join(pkgDir, filepath.FromSlash(strings.TrimPrefix(pattern, "all:"))))
+			if err != nil {
+				// 无效的模式由 go build 报错
+				continue
+			}
+			for _, match := range matches {
+				embedded, err := walkEmbedded(match, all)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, embedded...)
+			}
+		}
+	}
+	return files, scanner.Err()
+}
+
+// walkEmbedded 嵌入的文件或目录中的所有文件
+func walkEmbedded(root string, all bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if path != root && !all && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// embedPatterns 解析 //go:embed 指令中以空格分隔的模式, 模式可以使用双引号或反引号
+func embedPatterns(args string) []string {
+	var patterns []string
+	for args = strings.TrimSpace(args); len(args) > 0; args = strings.TrimSpace(args) {
+		var pattern string
+		switch args[0] {
+		case '"', '`':
+			end := strings.IndexByte(args[1:], args[0])
+			if end < 0 {
+				return patterns
+			}
+			quoted := args[:end+2]
+			args = args[end+2:]
+			unquoted, err := strconv.Unquote(quoted)
+			if err != nil {
+				continue
+			}
+			pattern = unquoted
+		default:
+			end := strings.IndexAny(args, " \t")
+			if end < 0 {
+				end = len(args)
+			}
+			pattern, args = args[:end], args[end:]
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+func hashFile(w io.Writer, rel, path string) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	_, _ = io.WriteString(w, rel)
+	_, _ = w.Write([]byte{0})
+	if _, err = io.Copy(w, fd); err != nil {
+		return err
+	}
+	_, _ = w.Write([]byte{0})
+	return nil
+}
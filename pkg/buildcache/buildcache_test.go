@@ -0,0 +1,119 @@
+package buildcache
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testProject 测试项目的文件
+var testProject = map[string]string{
+	"go.mod":                    "module demo\n",
+	"main.go":                   "package main\n",
+	"main_test.go":              "package main\n",
+	"README.md":                 "# demo\n",
+	"bin/server":                "binary",
+	"internal/bin/bin.go":       "package bin\n",
+	"pkg/node_modules/nm.go":    "package nm\n",
+	"testdata/data.go":          "package data\n",
+	".idea/workspace.xml":       "<xml/>",
+	"assets/assets.go":          "package assets\n\nimport \"embed\"\n\n//go:embed templates \"static/*.css\" `all:hidden`\nvar FS embed.FS\n",
+	"assets/templates/a.tmpl":   "a",
+	"assets/templates/.keep":    "",
+	"assets/static/app.css":     "body {}",
+	"assets/static/app.js":      "js",
+	"assets/hidden/.env":        "A=1",
+	"migrations/migrate.go":     "package migrations\n\nimport _ \"embed\"\n\n//go:embed 001_init.sql\nvar Init string\n",
+	"migrations/001_init.sql":   "create table a;",
+	"migrations/002_unused.sql": "create table b;",
+}
+
+func TestSum(t *testing.T) {
+	tests := []struct {
+		file    string
+		changed bool
+	}{
+		{"main.go", true},
+		{"go.mod", true},
+		{"internal/bin/bin.go", true},
+		{"pkg/node_modules/nm.go", true},
+		{"assets/templates/a.tmpl", true},
+		{"assets/static/app.css", true},
+		{"assets/hidden/.env", true},
+		{"migrations/001_init.sql", true},
+		{"main_test.go", false},
+		{"README.md", false},
+		{"bin/server", false},
+		{"testdata/data.go", false},
+		{".idea/workspace.xml", false},
+		{"assets/templates/.keep", false},
+		{"assets/static/app.js", false},
+		{"migrations/002_unused.sql", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range testProject {
+				write(t, filepath.Join(dir, name), content)
+			}
+			before, err := Sum(dir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			write(t, filepath.Join(dir, tt.file), testProject[tt.file]+"changed")
+			after, err := Sum(dir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed := before != after; changed != tt.changed {
+				t.Errorf("changing %s: sum changed = %v, want %v", tt.file, changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestSumBuildArgs(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "main.go"), "package main\n")
+	first, err := Sum(dir, []string{"build", "go1.20.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Sum(dir, []string{"build", "go1.21.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("Sum() is the same for different build args")
+	}
+}
+
+func TestEmbedPatterns(t *testing.T) {
+	tests := []struct {
+		args string
+		want []string
+	}{
+		{" a.txt", []string{"a.txt"}},
+		{" a.txt  b/*.sql\tc", []string{"a.txt", "b/*.sql", "c"}},
+		{` "with space.txt" ` + "`raw dir`", []string{"with space.txt", "raw dir"}},
+		{" all:static", []string{"all:static"}},
+		{` "unterminated`, nil},
+	}
+	for _, tt := range tests {
+		if got := embedPatterns(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("embedPatterns(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
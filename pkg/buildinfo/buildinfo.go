@@ -0,0 +1,175 @@
+package buildinfo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 查找最近的tag时最多回溯的提交数
+	maxDescribeDepth = 1000
+
+	SourceFlag    = "flag"
+	SourceGitTag  = "git tag"
+	SourceConfig  = "config"
+	SourceUnknown = "unknown"
+)
+
+// Info 编译时注入的版本信息
+type Info struct {
+	Version       string
+	VersionSource string
+	Commit        string
+	Dirty         bool
+	BuildTime     string
+	GoVersion     string
+}
+
+// Variables 版本信息注入的包变量, 如: main.Version, 为空时不注入
+type Variables struct {
+	Version   string
+	Commit    string
+	Dirty     string
+	BuildTime string
+	GoVersion string
+}
+
+// Collect 收集版本信息, 版本号的优先级: version参数 > git tag > configVersion
+func Collect(dir, version, configVersion string) *Info {
+	info := &Info{
+		Version:       version,
+		VersionSource: SourceFlag,
+		BuildTime:     time.Now().UTC().Format(time.RFC3339),
+		GoVersion:     goVersion(),
+	}
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err == nil {
+		if head, err := repo.Head(); err == nil {
+			info.Commit = head.Hash().String()
+			if len(info.Version) == 0 {
+				if tag, err := describe(repo, head.Hash()); err == nil {
+					info.Version, info.VersionSource = tag, SourceGitTag
+				}
+			}
+		}
+		if wt, err := repo.Worktree(); err == nil {
+			if status, err := wt.Status(); err == nil {
+				info.Dirty = !status.IsClean()
+			}
+		}
+	}
+	if len(info.Version) == 0 && len(configVersion) > 0 {
+		info.Version, info.VersionSource = configVersion, SourceConfig
+	}
+	if len(info.Version) == 0 {
+		info.Version, info.VersionSource = "unknown", SourceUnknown
+	}
+	return info
+}
+
+// LDFlags 生成 -X 参数
+func (i *Info) LDFlags(vars Variables) []string {
+	var (
+		flags  = make([]string, 0, 5)
+		values = [][2]string{
+			{vars.Version, i.Version},
+			{vars.Commit, i.Commit},
+			{vars.Dirty, strconv.FormatBool(i.Dirty)},
+			{vars.BuildTime, i.BuildTime},
+			{vars.GoVersion, i.GoVersion},
+		}
+	)
+	for _, v := range values {
+		if len(v[0]) == 0 {
+			continue
+		}
+		flag := fmt.Sprintf("-X %s=%s", v[0], v[1])
+		if strings.ContainsAny(v[1], " \t") {
+			flag = fmt.Sprintf("-X '%s=%s'", v[0], v[1])
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+// describe 类似 git describe --tags: 当前提交有tag时返回tag, 否则返回 <tag>-<距离>-g<短哈希>
+func describe(repo *git.Repository, head plumbing.Hash) (string, error) {
+	tags, err := tagsByCommit(repo)
+	if err != nil {
+		return "", err
+	}
+	if len(tags) == 0 {
+		return "", errors.New("no tags found")
+	}
+	commits, err := repo.Log(&git.LogOptions{From: head, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return "", err
+	}
+	defer commits.Close()
+
+	var (
+		result   string
+		distance = 0
+	)
+	err = commits.ForEach(func(c *object.Commit) error {
+		if tag, ok := tags[c.Hash]; ok {
+			result = tag
+			if distance > 0 {
+				result = fmt.Sprintf("%s-%d-g%s", tag, distance, head.String()[:7])
+			}
+			return storer.ErrStop
+		}
+		distance++
+		if distance > maxDescribeDepth {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(result) == 0 {
+		return "", errors.New("no reachable tags found")
+	}
+	return result, nil
+}
+
+// tagsByCommit 提交哈希到tag名的映射, 同时支持轻量tag和附注tag
+func tagsByCommit(repo *git.Repository) (map[plumbing.Hash]string, error) {
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[plumbing.Hash]string)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		hash := ref.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			hash = commit.Hash
+		}
+		if existing, ok := tags[hash]; !ok || ref.Name().Short() > existing {
+			tags[hash] = ref.Name().Short()
+		}
+		return nil
+	})
+	return tags, err
+}
+
+func goVersion() string {
+	output, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return SourceUnknown
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/json"
+	"github.com/stubborn-gaga-0805/aurora/pkg/kube"
+	"gopkg.in/yaml.v3"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// 配置项差异的类型
+const (
+	// DiffMissing 配置项只在左边的环境中存在
+	DiffMissing = "missing"
+	// DiffExtra 配置项只在右边的环境中存在
+	DiffExtra = "extra"
+	// DiffType 配置项的类型不同, 如: 10 和 "10"
+	DiffType = "type"
+	// DiffValue 配置项的值不同
+	DiffValue = "value"
+
+	// maxValueWidth 差异中展示的值的最大长度
+	maxValueWidth = 40
+)
+
+// DiffOptions 比较配置的参数
+type DiffOptions struct {
+	// IgnoreValues 忽略值的差异, 只比较配置项是否存在以及类型
+	IgnoreValues bool
+	// Allow 允许不同的配置项, 支持通配符 (如: data.*.addr), 匹配配置项本身或其上级
+	Allow []string
+}
+
+// Difference 配置项的差异
+type Difference struct {
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+	// Left、Right 两边的值, 配置项不存在时为空, 密码会被隐藏
+	Left      string `json:"left,omitempty"`
+	Right     string `json:"right,omitempty"`
+	LeftType  string `json:"leftType,omitempty"`
+	RightType string `json:"rightType,omitempty"`
+}
+
+// Diff 比较两个配置, 映射逐层比较 (配置项不区分大小写), 列表作为整体比较
+func Diff(left, right *yaml.Node, opts DiffOptions) []Difference {
+	d := &differ{opts: opts}
+	d.compare(left, right, nil)
+	return d.diffs
+}
+
+type differ struct {
+	opts  DiffOptions
+	diffs []Difference
+}
+
+func (d *differ) compare(left, right *yaml.Node, keys []string) {
+	left, right = resolve(left), resolve(right)
+	if len(keys) > 0 && d.allowed(keys) {
+		return
+	}
+	// 一边为空的映射或空值 (如: "data:") 时, 视为没有任何配置项, 逐项报告缺少或多出
+	if (isMapping(left) || isMapping(right)) && (isMapping(left) || isEmptyMapping(left)) && (isMapping(right) || isEmptyMapping(right)) {
+		for i := 0; i+1 < len(left.Content); i += 2 {
+			key := left.Content[i].Value
+			keyPath := append(append([]string{}, keys...), key)
+			value := findKey(right, key)
+			if value == nil {
+				if !d.allowed(keyPath) {
+					d.add(DiffMissing, keyPath, left.Content[i+1], nil)
+				}
+				continue
+			}
+			d.compare(left.Content[i+1], value, keyPath)
+		}
+		for i := 0; i+1 < len(right.Content); i += 2 {
+			key := right.Content[i].Value
+			keyPath := append(append([]string{}, keys...), key)
+			if findKey(left, key) == nil && !d.allowed(keyPath) {
+				d.add(DiffExtra, keyPath, nil, right.Content[i+1])
+			}
+		}
+		return
+	}
+	leftType, rightType := typeOf(left), typeOf(right)
+	// 空值可以是任意类型
+	if leftType != rightType && leftType != "null" && rightType != "null" {
+		d.add(DiffType, keys, left, right)
+		return
+	}
+	if !d.opts.IgnoreValues && !reflect.DeepEqual(decode(left), decode(right)) {
+		d.add(DiffValue, keys, left, right)
+	}
+}
+
+func (d *differ) add(kind string, keys []string, left, right *yaml.Node) {
+	diff := Difference{Kind: kind, Key: strings.Join(keys, ".")}
+	secret := kube.IsSecretKey(keys[len(keys)-1])
+	if left != nil {
+		diff.Left, diff.LeftType = render(left, secret), typeOf(left)
+	}
+	if right != nil {
+		diff.Right, diff.RightType = render(right, secret), typeOf(right)
+	}
+	d.diffs = append(d.diffs, diff)
+}
+
+// allowed 配置项或其上级是否在允许不同的列表中
+func (d *differ) allowed(keyPath []string) bool {
+	for _, pattern := range d.opts.Allow {
+		pattern = strings.ToLower(pattern)
+		for i := 1; i <= len(keyPath); i++ {
+			if ok, _ := path.Match(pattern, strings.ToLower(strings.Join(keyPath[:i], "."))); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isMapping(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode && len(node.Content) > 0
+}
+
+// isEmptyMapping 空的映射 ({}) 或空值
+func isEmptyMapping(node *yaml.Node) bool {
+	return (node.Kind == yaml.MappingNode && len(node.Content) == 0) || isNull(node)
+}
+
+// typeOf 配置项的类型
+func typeOf(node *yaml.Node) string {
+	node = resolve(node)
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "map"
+	case yaml.SequenceNode:
+		return "list"
+	}
+	switch node.Tag {
+	case "!!int":
+		return "int"
+	case "!!float":
+		return "float"
+	case "!!bool":
+		return "bool"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+func decode(node *yaml.Node) interface{} {
+	var value interface{}
+	_ = node.Decode(&value)
+	return value
+}
+
+// render 展示的值, 过长时截断
+func render(node *yaml.Node, secret bool) string {
+	node = resolve(node)
+	if node.Kind == yaml.ScalarNode {
+		if secret && len(node.Value) > 0 {
+			return "******"
+		}
+		if node.Tag == "!!str" {
+			return truncate(string(mustJSON(node.Value)))
+		}
+		return truncate(node.Value)
+	}
+	if secret {
+		return "******"
+	}
+	return truncate(string(mustJSON(decode(node))))
+}
+
+func mustJSON(value interface{}) []byte {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return []byte("?")
+	}
+	return content
+}
+
+func truncate(value string) string {
+	if runes := []rune(value); len(runes) > maxValueWidth {
+		return string(runes[:maxValueWidth-3]) + "..."
+	}
+	return value
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		opts        DiffOptions
+		want        []Difference
+	}{
+		{
+			name:  "identical",
+			left:  "data:\n  db:\n    addr: a\n",
+			right: "data:\n  db:\n    addr: a\n",
+		},
+		{
+			name:  "keys are compared case-insensitively",
+			left:  "data:\n  maxIdleConn: 1\n",
+			right: "Data:\n  MAXIDLECONN: 1\n",
+		},
+		{
+			name:  "missing and extra keys",
+			left:  "a: 1\nb: 2\n",
+			right: "b: 2\nc: 3\n",
+			want: []Difference{
+				{Kind: DiffMissing, Key: "a", Left: "1", LeftType: "int"},
+				{Kind: DiffExtra, Key: "c", Right: "3", RightType: "int"},
+			},
+		},
+		{
+			name:  "different types",
+			left:  "port: 10\n",
+			right: "port: \"10\"\n",
+			want:  []Difference{{Kind: DiffType, Key: "port", Left: "10", LeftType: "int", Right: `"10"`, RightType: "string"}},
+		},
+		{
+			name:  "different values",
+			left:  "addr: a\n",
+			right: "addr: b\n",
+			want:  []Difference{{Kind: DiffValue, Key: "addr", Left: `"a"`, LeftType: "string", Right: `"b"`, RightType: "string"}},
+		},
+		{
+			name:  "values are ignored",
+			left:  "addr: a\n",
+			right: "addr: b\n",
+			opts:  DiffOptions{IgnoreValues: true},
+		},
+		{
+			name:  "a null value can be any type",
+			left:  "timeout: 10\n",
+			right: "timeout:\n",
+			opts:  DiffOptions{IgnoreValues: true},
+		},
+		{
+			name:  "lists are compared as a whole",
+			left:  "hosts: [a, b]\n",
+			right: "hosts: [a]\n",
+			want:  []Difference{{Kind: DiffValue, Key: "hosts", Left: `["a","b"]`, LeftType: "list", Right: `["a"]`, RightType: "list"}},
+		},
+		{
+			name:  "null section on the right",
+			left:  "data:\n  db:\n    addr: a\n",
+			right: "data:\n",
+			opts:  DiffOptions{IgnoreValues: true},
+			want:  []Difference{{Kind: DiffMissing, Key: "data.db", Left: `{"addr":"a"}`, LeftType: "map"}},
+		},
+		{
+			name:  "empty mapping on the left",
+			left:  "data: {}\n",
+			right: "data:\n  db:\n    addr: a\n",
+			opts:  DiffOptions{IgnoreValues: true},
+			want:  []Difference{{Kind: DiffExtra, Key: "data.db", Right: `{"addr":"a"}`, RightType: "map"}},
+		},
+		{
+			name:  "empty sections on both sides",
+			left:  "data: {}\n",
+			right: "data:\n",
+			opts:  DiffOptions{IgnoreValues: true},
+		},
+		{
+			name:  "allowed keys and their children",
+			left:  "data:\n  db:\n    addr: a\n  mq:\n    addr: a\n",
+			right: "data:\n  db:\n    addr: b\n",
+			opts:  DiffOptions{Allow: []string{"data.*.addr", "DATA.MQ"}},
+		},
+		{
+			name:  "passwords are masked",
+			left:  "password: a\n",
+			right: "password: b\n",
+			want:  []Difference{{Kind: DiffValue, Key: "password", Left: "******", LeftType: "string", Right: "******", RightType: "string"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(parse(t, tt.left), parse(t, tt.right), tt.opts)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func parse(t *testing.T, content string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc.Content[0]
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+)
+
+// durationPattern time.ParseDuration 支持的格式
+const durationPattern = `^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// Schema 根据 conf 中的结构体生成配置文件的 JSON Schema (draft-07), 用于编辑器的补全和校验
+//
+// 与 Validate 一致: 只约束 env 和 data 中的数据库连接, 其他顶层配置由项目自行定义
+func Schema() map[string]interface{} {
+	definitions := make(map[string]interface{})
+	return map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "aurora project config",
+		"type":        "object",
+		"definitions": definitions,
+		"properties": map[string]interface{}{
+			"env": schemaOf(envType, definitions),
+			"data": map[string]interface{}{
+				"type":        "object",
+				"description": "Connections, the entries with a driver are database connections",
+				"properties": map[string]interface{}{
+					"db": schemaOf(dbType, definitions),
+				},
+				// 设置了 driver 的连接必须是数据库连接
+				"additionalProperties": map[string]interface{}{
+					"if":   map[string]interface{}{"type": "object", "required": []string{"driver"}},
+					"then": schemaOf(dbType, definitions),
+				},
+			},
+		},
+	}
+}
+
+func schemaOf(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if e, ok := reflect.New(t).Elem().Interface().(enum); ok {
+		return map[string]interface{}{"type": "string", "enum": e.Enum()}
+	}
+	if t == durationType {
+		return map[string]interface{}{
+			"description": "A duration, eg: 500ms, 5s, 1m30s, or nanoseconds",
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string", "pattern": durationPattern},
+				map[string]interface{}{"type": "integer"},
+			},
+		}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), definitions)}
+	case reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+		if _, ok := definitions[t.Name()]; ok {
+			return ref
+		}
+		// 先占位, 避免递归的结构体无限展开
+		definitions[t.Name()] = nil
+		var (
+			properties = make(map[string]interface{})
+			required   []string
+		)
+		for _, field := range Fields(t) {
+			properties[field.Name] = schemaOf(field.Type, definitions)
+			if field.Required {
+				required = append(required, field.Name)
+			}
+		}
+		definition := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			definition["required"] = required
+		}
+		definitions[t.Name()] = definition
+		return ref
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,364 @@
+package config
+
+import (
+	"fmt"
+	"github.com/stubborn-gaga-0805/aurora/conf"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	envType      = reflect.TypeOf(conf.Env{})
+	dbType       = reflect.TypeOf(conf.DB{})
+
+	yamlLinePattern = regexp.MustCompile(`line (\d+): `)
+)
+
+// enum 取值有限的配置类型, 如: conf.DBDriver
+type enum interface {
+	Enum() []string
+}
+
+// Issue 配置文件中的错误
+type Issue struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Path 配置项的路径, 如: data.main.driver
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	position := fmt.Sprintf("%s:%d", i.File, i.Line)
+	if i.Column > 0 {
+		position += fmt.Sprintf(":%d", i.Column)
+	}
+	if len(i.Path) == 0 {
+		return fmt.Sprintf("%s: %s", position, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", position, i.Path, i.Message)
+}
+
+// ValidateFiles 校验分层合并后的配置 (如: config.yaml, config.<env>.yaml), 只校验 aurora 能识别的部分:
+// env 对应 conf.Env, data 中设置了 driver 的连接 (以及 data.db) 对应 conf.DB. 这些部分不允许未知的配置项,
+// 并检查枚举值、时长以及必填项, 其他顶层配置由项目自行定义, 不做校验
+func ValidateFiles(files ...string) ([]Issue, error) {
+	var (
+		root = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		v    = &validator{origins: make(map[*yaml.Node]string)}
+	)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		node := v.parse(file, content)
+		if node == nil {
+			continue
+		}
+		v.checkDuplicates(node, "")
+		conf.MergeNodes(root, node)
+	}
+	if len(v.issues) == 0 {
+		v.validate(root)
+	}
+	// 按照在文件中的位置排序
+	order := make(map[string]int, len(files))
+	for i, file := range files {
+		order[file] = i
+	}
+	sort.SliceStable(v.issues, func(i, j int) bool {
+		a, b := v.issues[i], v.issues[j]
+		if a.File != b.File {
+			return order[a.File] < order[b.File]
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.issues, nil
+}
+
+// parse 解析配置文件, 并记录每个节点所在的文件
+func (v *validator) parse(file string, content []byte) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		var (
+			line    int
+			message = strings.TrimPrefix(err.Error(), "yaml: ")
+		)
+		if match := yamlLinePattern.FindStringSubmatch(message); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			message = strings.Replace(message, match[0], "", 1)
+		}
+		v.issues = append(v.issues, Issue{File: file, Line: line, Message: message})
+		return nil
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	v.track(doc.Content[0], file)
+	root := resolve(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		v.add(root, "", "the config must be a mapping")
+		return nil
+	}
+	return root
+}
+
+func (v *validator) track(node *yaml.Node, file string) {
+	v.origins[node] = file
+	for _, child := range node.Content {
+		v.track(child, file)
+	}
+}
+
+// validate 校验合并后的配置
+func (v *validator) validate(root *yaml.Node) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], resolve(root.Content[i+1])
+		switch strings.ToLower(key.Value) {
+		case "env":
+			v.walk(value, envType, "env")
+		case "data":
+			v.walkData(value)
+		}
+	}
+	return
+}
+
+type validator struct {
+	// origins 节点所在的配置文件
+	origins map[*yaml.Node]string
+	issues  []Issue
+}
+
+func (v *validator) add(node *yaml.Node, path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{File: v.origins[node], Line: node.Line, Column: node.Column, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// walkData data 中设置了 driver 的连接为数据库连接, 其他连接 (如: redis) 由项目自行定义
+func (v *validator) walkData(node *yaml.Node) {
+	if isNull(node) {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		v.add(node, "data", "expected a mapping")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i].Value, resolve(node.Content[i+1])
+		if strings.EqualFold(name, "db") || (value.Kind == yaml.MappingNode && findKey(value, "driver") != nil) {
+			v.walk(value, dbType, "data."+name)
+		}
+	}
+}
+
+func (v *validator) walk(node *yaml.Node, t reflect.Type, path string) {
+	node = resolve(node)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isNull(node) {
+		return
+	}
+	if e, ok := reflect.New(t).Elem().Interface().(enum); ok {
+		if node.Kind != yaml.ScalarNode {
+			v.add(node, path, "expected one of: %s", strings.Join(e.Enum(), ", "))
+			return
+		}
+		for _, value := range e.Enum() {
+			if value == node.Value {
+				return
+			}
+		}
+		v.add(node, path, "unsupported value %q, expected one of: %s", node.Value, strings.Join(e.Enum(), ", "))
+		return
+	}
+	if t == durationType {
+		if node.Kind != yaml.ScalarNode {
+			v.add(node, path, "expected a duration, eg: 5s")
+			return
+		}
+		if node.Tag == "!!int" {
+			return
+		}
+		if _, err := time.ParseDuration(node.Value); err != nil {
+			v.add(node, path, "invalid duration %q, eg: 500ms, 5s, 1m30s", node.Value)
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			v.add(node, path, "expected a string")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if _, err := strconv.Atoi(node.Value); node.Kind != yaml.ScalarNode || err != nil {
+			v.add(node, path, "expected an integer")
+		}
+	case reflect.Bool:
+		if _, err := strconv.ParseBool(node.Value); node.Kind != yaml.ScalarNode || err != nil {
+			v.add(node, path, "expected a boolean")
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.add(node, path, "expected a list")
+			return
+		}
+		for i, item := range node.Content {
+			v.walk(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.add(node, path, "expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.walk(node.Content[i+1], t.Elem(), join(path, node.Content[i].Value))
+		}
+	case reflect.Struct:
+		v.walkStruct(node, t, path)
+	}
+}
+
+func (v *validator) walkStruct(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.add(node, path, "expected a mapping")
+		return
+	}
+	fields := Fields(t)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Value == "<<" {
+			continue
+		}
+		field, ok := lookupField(fields, key.Value)
+		if !ok {
+			v.add(key, join(path, key.Value), "unknown key, expected one of: %s", strings.Join(fieldNames(fields), ", "))
+			continue
+		}
+		v.walk(node.Content[i+1], field.Type, join(path, key.Value))
+	}
+	for _, field := range fields {
+		if !field.Required {
+			continue
+		}
+		value := findKey(node, field.Name)
+		if value == nil || isNull(value) || (value.Kind == yaml.ScalarNode && len(value.Value) == 0) {
+			v.add(node, join(path, field.Name), "is required")
+		}
+	}
+}
+
+// checkDuplicates 检查文件中所有映射里重复的配置项, 后面的值会覆盖前面的值
+func (v *validator) checkDuplicates(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		seen := make(map[string]bool, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := strings.ToLower(node.Content[i].Value)
+			if seen[key] {
+				v.add(node.Content[i], join(path, node.Content[i].Value), "duplicate key")
+			}
+			seen[key] = true
+			v.checkDuplicates(node.Content[i+1], join(path, node.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			v.checkDuplicates(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// Field 配置结构体的字段
+type Field struct {
+	Name     string
+	Type     reflect.Type
+	Required bool
+}
+
+// Fields 结构体中可以配置的字段, 字段名优先使用 yaml tag, 其次是 json tag, tag 为 "-" 的字段不能配置
+func Fields(t reflect.Type) []Field {
+	fields := make([]Field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := tagName(f, "yaml")
+		if len(name) == 0 {
+			name = tagName(f, "json")
+		}
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+		fields = append(fields, Field{Name: name, Type: f.Type, Required: f.Tag.Get("validate") == "required"})
+	}
+	return fields
+}
+
+func tagName(f reflect.StructField, key string) string {
+	tag, ok := f.Tag.Lookup(key)
+	if !ok {
+		return ""
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+// lookupField 查找配置项对应的字段, 与viper一样不区分大小写
+func lookupField(fields []Field, key string) (Field, bool) {
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, key) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+func fieldNames(fields []Field) []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func findKey(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return resolve(node.Content[i+1])
+		}
+	}
+	return nil
+}
+
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func join(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
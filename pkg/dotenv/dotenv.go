@@ -0,0 +1,206 @@
+package dotenv
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	SourceProcess = "process"
+	SourceAurora  = "aurora"
+)
+
+var (
+	ErrInvalidLine = errors.New("invalid line")
+
+	keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+)
+
+// Env 合并后的环境变量
+//
+// 优先级(由低到高): env文件(按加载顺序, 后加载的覆盖先加载的) < 进程环境变量 < aurora设置的变量
+type Env struct {
+	values  map[string]string
+	sources map[string]string
+}
+
+func New() *Env {
+	return &Env{
+		values:  make(map[string]string),
+		sources: make(map[string]string),
+	}
+}
+
+// Load 依次加载env文件, 并叠加当前进程的环境变量
+func Load(files ...string) (*Env, error) {
+	env := New()
+	for _, file := range files {
+		if err := env.LoadFile(file); err != nil {
+			return nil, err
+		}
+	}
+	env.Overlay(os.Environ(), SourceProcess)
+	return env, nil
+}
+
+// LoadFile 加载单个env文件
+func (e *Env) LoadFile(path string) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	return e.parse(fd, path)
+}
+
+// Overlay 用 KEY=VALUE 格式的变量覆盖当前的值
+func (e *Env) Overlay(environ []string, source string) {
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || len(key) == 0 {
+			continue
+		}
+		e.Set(key, value, source)
+	}
+}
+
+func (e *Env) Set(key, value, source string) {
+	e.values[key] = value
+	e.sources[key] = source
+}
+
+// Lookup 查找变量, 进程环境变量优先于env文件, 与最终合并的优先级保持一致
+func (e *Env) Lookup(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	value, ok := e.values[key]
+	return value, ok
+}
+
+// Source 变量的来源 (文件路径、process 或 aurora)
+func (e *Env) Source(key string) string {
+	return e.sources[key]
+}
+
+func (e *Env) Keys() []string {
+	keys := make([]string, 0, len(e.values))
+	for key := range e.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Environ 转换为 exec.Cmd.Env 可用的格式
+func (e *Env) Environ() []string {
+	environ := make([]string, 0, len(e.values))
+	for _, key := range e.Keys() {
+		environ = append(environ, fmt.Sprintf("%s=%s", key, e.values[key]))
+	}
+	return environ
+}
+
+func (e *Env) Get(key string) string {
+	return e.values[key]
+}
+
+func (e *Env) parse(r io.Reader, source string) error {
+	var (
+		lineNo  = 0
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		key, raw, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !keyPattern.MatchString(key) {
+			return fmt.Errorf("%s:%d: %w: %q", source, lineNo, ErrInvalidLine, line)
+		}
+		value, err := e.parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", source, lineNo, err)
+		}
+		e.Set(key, value, source)
+	}
+	return scanner.Err()
+}
+
+func (e *Env) parseValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		// 单引号内的内容不做任何处理
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", errors.New("unterminated single-quoted value")
+		}
+		return raw[1 : end+1], nil
+	case strings.HasPrefix(raw, `"`):
+		// 变量在扫描时展开, 转义的 \$ 不再展开
+		var (
+			sb      strings.Builder
+			pending strings.Builder
+			escaped bool
+		)
+		flush := func() {
+			sb.WriteString(e.expand(pending.String()))
+			pending.Reset()
+		}
+		for _, c := range raw[1:] {
+			if escaped {
+				switch c {
+				case 'n':
+					pending.WriteRune('\n')
+				case 't':
+					pending.WriteRune('\t')
+				case '$':
+					flush()
+					sb.WriteRune('$')
+				default:
+					pending.WriteRune(c)
+				}
+				escaped = false
+				continue
+			}
+			if c == '\\' {
+				escaped = true
+				continue
+			}
+			if c == '"' {
+				flush()
+				return sb.String(), nil
+			}
+			pending.WriteRune(c)
+		}
+		return "", errors.New("unterminated double-quoted value")
+	default:
+		// 去掉行内注释
+		if idx := strings.Index(raw, " #"); idx >= 0 {
+			raw = strings.TrimSpace(raw[:idx])
+		}
+		return e.expand(raw), nil
+	}
+}
+
+// expand 展开 ${VAR}、$VAR 和 ${VAR:-default}
+func (e *Env) expand(value string) string {
+	return os.Expand(value, func(name string) string {
+		name, def, hasDefault := strings.Cut(name, ":-")
+		if v, ok := e.Lookup(name); ok && (len(v) > 0 || !hasDefault) {
+			return v
+		}
+		return def
+	})
+}
@@ -0,0 +1,107 @@
+package dotenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain value", content: "A=1", key: "A", want: "1"},
+		{name: "spaces around", content: "  A =  1  ", key: "A", want: "1"},
+		{name: "empty value", content: "A=", key: "A", want: ""},
+		{name: "value containing =", content: "DSN=user:pw@tcp(host)/db?a=b", key: "DSN", want: "user:pw@tcp(host)/db?a=b"},
+		{name: "export prefix", content: "export A=1", key: "A", want: "1"},
+		{name: "inline comment", content: "A=1 # comment", key: "A", want: "1"},
+		{name: "# without a space is kept", content: "A=a#b", key: "A", want: "a#b"},
+		{name: "comments and blank lines", content: "# comment\n\n  # indented\nA=1\n", key: "A", want: "1"},
+		{name: "single quotes are literal", content: `A='$B \n # x'`, key: "A", want: `$B \n # x`},
+		{name: "double quotes keep # and spaces", content: `A=" a # b "`, key: "A", want: " a # b "},
+		{name: "double-quoted escapes", content: `A="a\nb\tc\"d\\e"`, key: "A", want: "a\nb\tc\"d\\e"},
+		{name: "comment after double quotes", content: `A="a" # comment`, key: "A", want: "a"},
+		{name: "expand from the file", content: "B=x\nA=${B}-$B", key: "A", want: "x-x"},
+		{name: "expand in double quotes", content: "B=x\nA=\"${B}-y\"", key: "A", want: "x-y"},
+		{name: "escaped $ in double quotes is literal", content: "ss=x\nA=\"p\\$ss\"", key: "A", want: "p$ss"},
+		{name: "escaped ${} in double quotes is literal", content: "B=x\nA=\"\\${B}:$B\"", key: "A", want: "${B}:x"},
+		{name: "default for an unset var", content: "A=${NOT_SET_IN_TEST:-dflt}", key: "A", want: "dflt"},
+		{name: "default for an empty var", content: "B=\nA=${B:-dflt}", key: "A", want: "dflt"},
+		{name: "no default for a set var", content: "B=x\nA=${B:-dflt}", key: "A", want: "x"},
+		{name: "unset var without default", content: "A=a${NOT_SET_IN_TEST}b", key: "A", want: "ab"},
+		{name: "later lines override", content: "A=1\nA=2", key: "A", want: "2"},
+		{name: "unterminated single quote", content: "A='x", wantErr: true},
+		{name: "unterminated double quote", content: `A="x`, wantErr: true},
+		{name: "missing =", content: "A", wantErr: true},
+		{name: "invalid key", content: "1A=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := New()
+			err := env.parse(strings.NewReader(tt.content), ".env")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := env.Get(tt.key); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalidLine(t *testing.T) {
+	err := New().parse(strings.NewReader("A=1\nnot a line\n"), ".env")
+	if !errors.Is(err, ErrInvalidLine) || !strings.HasPrefix(err.Error(), ".env:2:") {
+		t.Errorf("parse() error = %v, want ErrInvalidLine at .env:2", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	var (
+		base  = filepath.Join(dir, ".env")
+		local = filepath.Join(dir, ".env.local")
+	)
+	for path, content := range map[string]string{
+		base:  "FROM_FILE=base\nOVERRIDDEN=base\nFROM_PROCESS=file\nREF=$FROM_PROCESS\n",
+		local: "OVERRIDDEN=local\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("FROM_PROCESS", "process")
+	env, err := Load(base, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Set("FROM_AURORA", "aurora", SourceAurora)
+	tests := []struct {
+		key, value, source string
+	}{
+		{"FROM_FILE", "base", base},
+		{"OVERRIDDEN", "local", local},
+		{"FROM_PROCESS", "process", SourceProcess},
+		// 展开时进程环境变量优先于env文件
+		{"REF", "process", base},
+		{"FROM_AURORA", "aurora", SourceAurora},
+	}
+	for _, tt := range tests {
+		if value, source := env.Get(tt.key), env.Source(tt.key); value != tt.value || source != tt.source {
+			t.Errorf("%s = %q (%s), want %q (%s)", tt.key, value, source, tt.value, tt.source)
+		}
+	}
+	if _, err = Load(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Load() with a missing file, want an error")
+	}
+}
@@ -0,0 +1,76 @@
+package kube
+
+import (
+	"bytes"
+	"github.com/stubborn-gaga-0805/aurora/conf"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+)
+
+// SecretEnvPrefix 密码通过环境变量注入时使用的前缀, 如: data.db.password -> AURORA_DATA_DB_PASSWORD
+const SecretEnvPrefix = conf.EnvPrefix
+
+// SplitSecrets 将配置文件中的密码移除, 返回移除后的配置以及 环境变量名 -> 密码 的映射
+//
+// 服务加载配置时环境变量会覆盖配置文件中的同名配置项, 密码可以通过 Secret 注入而不必写入 ConfigMap
+func SplitSecrets(content []byte) ([]byte, map[string]string, error) {
+	var (
+		doc     yaml.Node
+		secrets = make(map[string]string)
+	)
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, err
+	}
+	splitSecrets(&doc, nil, secrets)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), secrets, nil
+}
+
+// SecretEnvName 配置项对应的环境变量名
+func SecretEnvName(path []string) string {
+	return SecretEnvPrefix + strings.ToUpper(strings.Join(path, "_"))
+}
+
+func splitSecrets(node *yaml.Node, path []string, secrets map[string]string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			splitSecrets(child, path, secrets)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			splitSecrets(child, append(append([]string{}, path...), strconv.Itoa(i)), secrets)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := append(append([]string{}, path...), key.Value)
+			if value.Kind == yaml.ScalarNode && IsSecretKey(key.Value) {
+				if len(value.Value) > 0 {
+					secrets[SecretEnvName(childPath)] = value.Value
+				}
+				value.Value = ""
+				value.Tag = "!!str"
+				value.Style = yaml.DoubleQuotedStyle
+				continue
+			}
+			splitSecrets(value, childPath, secrets)
+		}
+	}
+}
+
+// IsSecretKey 配置项是否为密码
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.Contains(key, "password") || strings.Contains(key, "secret")
+}
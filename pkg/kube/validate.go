@@ -0,0 +1,218 @@
+package kube
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KustomizationFile kustomize 的配置文件名
+const KustomizationFile = "kustomization.yaml"
+
+var restartPolicies = map[string][]string{
+	"Deployment": {"Always"},
+	"CronJob":    {"OnFailure", "Never"},
+}
+
+// Validate 在本地校验目录下的 Kubernetes 资源和 kustomization 文件, 不需要连接集群
+func Validate(dir string) []error {
+	var errs []error
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, e := range ValidateFile(path, content) {
+			errs = append(errs, fmt.Errorf("%s: %w", path, e))
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// ValidateFile 校验单个文件中的所有YAML文档
+func ValidateFile(path string, content []byte) []error {
+	var (
+		errs    []error
+		decoder = yaml.NewDecoder(bytes.NewReader(content))
+	)
+	for i := 0; ; i++ {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return append(errs, err)
+		}
+		if doc == nil {
+			continue
+		}
+		r := resource(doc)
+		if filepath.Base(path) == KustomizationFile {
+			errs = append(errs, r.validateKustomization(filepath.Dir(path))...)
+			continue
+		}
+		for _, err := range r.validate() {
+			errs = append(errs, fmt.Errorf("document %d (%s %s): %w", i, r.str("kind"), r.str("metadata.name"), err))
+		}
+	}
+	return errs
+}
+
+type resource map[string]interface{}
+
+func (r resource) validate() (errs []error) {
+	for _, field := range []string{"apiVersion", "kind", "metadata.name"} {
+		if len(r.str(field)) == 0 {
+			errs = append(errs, fmt.Errorf("%s is required", field))
+		}
+	}
+	switch r.str("kind") {
+	case "Deployment":
+		errs = append(errs, r.validateSelector("spec.selector.matchLabels", "spec.template.metadata.labels")...)
+		errs = append(errs, r.validatePodSpec("spec.template.spec")...)
+	case "Service":
+		if len(r.list("spec.ports")) == 0 {
+			errs = append(errs, errors.New("spec.ports is required"))
+		}
+		if len(r.mapping("spec.selector")) == 0 {
+			errs = append(errs, errors.New("spec.selector is required"))
+		}
+	case "CronJob":
+		if len(strings.Fields(r.str("spec.schedule"))) != 5 {
+			errs = append(errs, fmt.Errorf("spec.schedule %q must have 5 fields", r.str("spec.schedule")))
+		}
+		errs = append(errs, r.validatePodSpec("spec.jobTemplate.spec.template.spec")...)
+	case "ConfigMap", "Secret":
+		for _, field := range []string{"data", "stringData"} {
+			for key, value := range r.mapping(field) {
+				if _, ok := value.(string); !ok {
+					errs = append(errs, fmt.Errorf("%s.%s must be a string", field, key))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func (r resource) validateSelector(selectorPath, labelsPath string) (errs []error) {
+	var (
+		selector = r.mapping(selectorPath)
+		labels   = r.mapping(labelsPath)
+	)
+	if len(selector) == 0 {
+		return []error{fmt.Errorf("%s is required", selectorPath)}
+	}
+	for key, value := range selector {
+		if labels[key] != value {
+			errs = append(errs, fmt.Errorf("%s does not match %s on %q", selectorPath, labelsPath, key))
+		}
+	}
+	return errs
+}
+
+func (r resource) validatePodSpec(path string) (errs []error) {
+	containers := r.list(path + ".containers")
+	if len(containers) == 0 {
+		return []error{fmt.Errorf("%s.containers is required", path)}
+	}
+	for i, c := range containers {
+		container, _ := c.(map[string]interface{})
+		for _, field := range []string{"name", "image"} {
+			if len(resource(container).str(field)) == 0 {
+				errs = append(errs, fmt.Errorf("%s.containers[%d].%s is required", path, i, field))
+			}
+		}
+	}
+	if policy := r.str(path + ".restartPolicy"); len(policy) > 0 {
+		allowed := restartPolicies[r.str("kind")]
+		if !contains(allowed, policy) {
+			errs = append(errs, fmt.Errorf("%s.restartPolicy %q must be one of %v", path, policy, allowed))
+		}
+	}
+	return errs
+}
+
+// validateKustomization 检查引用的资源文件以及env文件是否存在
+func (r resource) validateKustomization(dir string) (errs []error) {
+	for _, field := range []string{"resources", "patches"} {
+		for _, item := range r.list(field) {
+			path, ok := item.(string)
+			if !ok {
+				path = resource(asMap(item)).str("path")
+			}
+			if len(path) == 0 {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q not found", field, path))
+			}
+		}
+	}
+	// secretGenerator/configMapGenerator 读取的env文件
+	for _, field := range []string{"secretGenerator", "configMapGenerator"} {
+		for _, item := range r.list(field) {
+			for _, env := range resource(asMap(item)).list("envs") {
+				path, _ := env.(string)
+				if _, err := os.Stat(filepath.Join(dir, path)); len(path) > 0 && err != nil {
+					errs = append(errs, fmt.Errorf("%s: %q not found", field, path))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func (r resource) get(path string) interface{} {
+	var current interface{} = map[string]interface{}(r)
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+func (r resource) str(path string) string {
+	s, _ := r.get(path).(string)
+	return s
+}
+
+func (r resource) list(path string) []interface{} {
+	l, _ := r.get(path).([]interface{})
+	return l
+}
+
+func (r resource) mapping(path string) map[string]interface{} {
+	return asMap(r.get(path))
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/samber/lo"
+	"sort"
+	"strings"
+)
+
+// 禁用某个版本信息变量的注入
+const disabledVariable = "-"
+
+var ErrUnknownProfile = errors.New("unknown build profile")
+
+// Build 编译相关的配置
+type Build struct {
+	// Profile aurora build 默认使用的编译配置 (默认: release)
+	Profile string `yaml:"profile"`
+	// RunProfile run、job、cron 默认使用的编译配置, 为空时不使用任何编译参数
+	RunProfile string `yaml:"runProfile"`
+	// Profiles 项目自定义的编译配置
+	Profiles map[string]Profile `yaml:"profiles"`
+	// Variables 编译时通过 -ldflags "-X" 注入版本信息的包变量
+	Variables Variables `yaml:"variables"`
+}
+
+// Variables 版本信息注入的包变量, 未设置时使用默认值 (如: main.Version), 设置为 "-" 时不注入
+type Variables struct {
+	Version   string `yaml:"version"`
+	Commit    string `yaml:"commit"`
+	Dirty     string `yaml:"dirty"`
+	BuildTime string `yaml:"buildTime"`
+	GoVersion string `yaml:"goVersion"`
+}
+
+// Resolve 填充默认值并去掉禁用的变量
+func (v Variables) Resolve() Variables {
+	return Variables{
+		Version:   resolveVariable(v.Version, "main.Version"),
+		Commit:    resolveVariable(v.Commit, "main.Commit"),
+		Dirty:     resolveVariable(v.Dirty, "main.Dirty"),
+		BuildTime: resolveVariable(v.BuildTime, "main.BuildTime"),
+		GoVersion: resolveVariable(v.GoVersion, "main.GoVersion"),
+	}
+}
+
+func resolveVariable(value, defaultValue string) string {
+	switch value {
+	case "":
+		return defaultValue
+	case disabledVariable:
+		return ""
+	}
+	return value
+}
+
+const (
+	ProfileRelease = "release"
+	ProfileDebug   = "debug"
+	ProfileRace    = "race"
+)
+
+// 内置的编译配置, 项目中声明的同名配置会覆盖内置配置
+var builtinProfiles = map[string]Profile{
+	ProfileRelease: {LDFlags: "-s -w", TrimPath: true},
+	ProfileDebug:   {GCFlags: "all=-N -l"},
+	ProfileRace:    {Race: true, CGO: boolPtr(true)},
+}
+
+// Profile 编译配置
+type Profile struct {
+	Tags     []string          `yaml:"tags"`
+	GCFlags  string            `yaml:"gcflags"`
+	LDFlags  string            `yaml:"ldflags"`
+	TrimPath bool              `yaml:"trimpath"`
+	Race     bool              `yaml:"race"`
+	CGO      *bool             `yaml:"cgo"`
+	Env      map[string]string `yaml:"env"`
+}
+
+// GetProfile 获取指定名称的编译配置
+func (b Build) GetProfile(name string) (Profile, error) {
+	if profile, ok := b.Profiles[name]; ok {
+		return profile, nil
+	}
+	if profile, ok := builtinProfiles[name]; ok {
+		return profile, nil
+	}
+	return Profile{}, fmt.Errorf("%w: %q, available: %v", ErrUnknownProfile, name, b.ProfileNames())
+}
+
+// ProfileNames 所有可用的编译配置名称
+func (b Build) ProfileNames() []string {
+	names := lo.Keys(builtinProfiles)
+	for name := range b.Profiles {
+		if !lo.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuildProfile aurora build 默认使用的编译配置
+func (b Build) BuildProfile() string {
+	if len(b.Profile) == 0 {
+		return ProfileRelease
+	}
+	return b.Profile
+}
+
+// Args 转换为 go build 的参数, extraLDFlags 会追加到 -ldflags 中
+func (p Profile) Args(extraLDFlags ...string) []string {
+	var (
+		args    = make([]string, 0, 6)
+		ldflags = make([]string, 0, len(extraLDFlags)+1)
+	)
+	if len(p.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(p.Tags, ","))
+	}
+	if len(p.GCFlags) > 0 {
+		args = append(args, "-gcflags="+p.GCFlags)
+	}
+	if len(p.LDFlags) > 0 {
+		ldflags = append(ldflags, p.LDFlags)
+	}
+	ldflags = append(ldflags, extraLDFlags...)
+	if len(ldflags) > 0 {
+		args = append(args, "-ldflags="+strings.Join(ldflags, " "))
+	}
+	if p.TrimPath {
+		args = append(args, "-trimpath")
+	}
+	if p.Race {
+		args = append(args, "-race")
+	}
+	return args
+}
+
+// Environ 编译时额外设置的环境变量
+func (p Profile) Environ() []string {
+	environ := make([]string, 0, len(p.Env)+1)
+	if p.CGO != nil {
+		environ = append(environ, fmt.Sprintf("CGO_ENABLED=%d", lo.Ternary(*p.CGO, 1, 0)))
+	}
+	keys := lo.Keys(p.Env)
+	sort.Strings(keys)
+	for _, key := range keys {
+		environ = append(environ, fmt.Sprintf("%s=%s", key, p.Env[key]))
+	}
+	return environ
+}
+
+func (b Build) validate() error {
+	for _, name := range []string{b.Profile, b.RunProfile} {
+		if len(name) == 0 {
+			continue
+		}
+		if _, err := b.GetProfile(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
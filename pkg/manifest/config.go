@@ -0,0 +1,21 @@
+package manifest
+
+import (
+	"fmt"
+	"path"
+)
+
+// Config 项目配置文件 (configs/config.<env>.yaml) 相关的配置
+type Config struct {
+	// DiffAllow aurora config diff 中允许在环境之间不同的配置项, 支持通配符, 如: data.*.addr
+	DiffAllow []string `yaml:"diffAllow"`
+}
+
+func (c Config) validate() error {
+	for i, pattern := range c.DiffAllow {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("diffAllow[%d]: invalid pattern %q", i, pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cron 定时任务相关的配置
+type Cron struct {
+	// Tasks 项目的定时任务, 用于生成 CronJob、systemd timer 等部署文件
+	Tasks []CronTask `yaml:"tasks"`
+}
+
+// CronTask 定时任务, 通过 "server job -n <job> -p <params>" 执行
+type CronTask struct {
+	Name string `yaml:"name"`
+	// Schedule 标准的5段式cron表达式, 如: "*/5 * * * *"
+	Schedule string `yaml:"schedule"`
+	// Job 执行的任务名, 默认与 Name 相同
+	Job    string   `yaml:"job"`
+	Params []string `yaml:"params"`
+}
+
+// JobName 执行的任务名
+func (t CronTask) JobName() string {
+	if len(t.Job) == 0 {
+		return t.Name
+	}
+	return t.Job
+}
+
+// Args 执行任务的命令参数
+func (t CronTask) Args() []string {
+	args := []string{"job", "-n", t.JobName()}
+	if len(t.Params) > 0 {
+		args = append(args, "-p", strings.Join(t.Params, ","))
+	}
+	return args
+}
+
+func (c Cron) validate() error {
+	names := make(map[string]bool, len(c.Tasks))
+	for i, task := range c.Tasks {
+		if len(task.Name) == 0 {
+			return fmt.Errorf("tasks[%d]: name is required", i)
+		}
+		if names[task.Name] {
+			return fmt.Errorf("tasks[%d]: duplicate task %q", i, task.Name)
+		}
+		names[task.Name] = true
+		if len(strings.Fields(task.Schedule)) != 5 {
+			return fmt.Errorf("tasks[%d]: schedule %q must have 5 fields", i, task.Schedule)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package manifest
+
+import (
+	"fmt"
+	"github.com/stubborn-gaga-0805/aurora/conf"
+	"github.com/stubborn-gaga-0805/aurora/consts"
+	"path/filepath"
+	"regexp"
+)
+
+// envNamePattern 环境名会用于配置文件名、kustomize overlay 目录等
+var envNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// DefaultEnvironments 项目没有声明 envs 时支持的运行环境
+var DefaultEnvironments = []Environment{
+	{Name: consts.EnvLocal, Debug: true},
+	{Name: consts.EnvDev, Debug: true},
+	{Name: consts.EnvTest, Debug: true},
+	{Name: consts.EnvPre},
+	{Name: consts.EnvProd},
+}
+
+// Environment 项目的运行环境
+type Environment struct {
+	Name string `yaml:"name"`
+	// Debug 是否为调试环境
+	Debug bool `yaml:"debug"`
+	// Protected 受保护的环境, 执行有破坏性的命令 (如: aurora job) 前需要确认
+	Protected bool `yaml:"protected"`
+	// Config 环境的配置文件名 (位于 configs 目录), 默认: config.<name>.yaml
+	Config string `yaml:"config"`
+}
+
+// ConfigFile 环境的配置文件名
+func (e Environment) ConfigFile() string {
+	if len(e.Config) == 0 {
+		return conf.ConfigFileName(e.Name)
+	}
+	return e.Config
+}
+
+// Environments 项目支持的运行环境, 第一个为默认环境
+func (m *Manifest) Environments() []Environment {
+	if len(m.Envs) == 0 {
+		return DefaultEnvironments
+	}
+	return m.Envs
+}
+
+func validateEnvs(envs []Environment) error {
+	var (
+		names = make(map[string]bool, len(envs))
+		files = make(map[string]string, len(envs))
+	)
+	for i, env := range envs {
+		if !envNamePattern.MatchString(env.Name) {
+			return fmt.Errorf("envs[%d]: invalid name %q, only lowercase letters, digits, '-' and '_' are allowed", i, env.Name)
+		}
+		if names[env.Name] {
+			return fmt.Errorf("envs[%d]: duplicate env %q", i, env.Name)
+		}
+		names[env.Name] = true
+		file := env.ConfigFile()
+		if file != filepath.Base(file) || file == ".." {
+			return fmt.Errorf("envs[%d]: config must be a file name in the configs directory, got %q", i, file)
+		}
+		if file == conf.BaseConfigFile {
+			return fmt.Errorf("envs[%d]: config %q is shared by all the envs", i, file)
+		}
+		if other, ok := files[file]; ok {
+			return fmt.Errorf("envs[%d]: config %q is already used by env %q", i, file, other)
+		}
+		files[file] = env.Name
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+)
+
+// FileName 项目配置文件, 位于项目根目录
+const FileName = "aurora.yaml"
+
+var ErrInvalidManifest = errors.New("invalid project manifest")
+
+// Manifest 项目配置
+type Manifest struct {
+	Runtime  Runtime  `yaml:"runtime"`
+	Build    Build    `yaml:"build"`
+	Cron     Cron     `yaml:"cron"`
+	Template Template `yaml:"template"`
+	Config   Config   `yaml:"config"`
+	// Envs 项目支持的运行环境, 没有声明时为 local、dev、test、pre、prod
+	Envs []Environment `yaml:"envs"`
+	// Tools aurora init 安装的工具
+	Tools []Tool `yaml:"tools"`
+
+	path string
+}
+
+// Runtime 服务运行相关的配置
+type Runtime struct {
+	// Args 服务支持的额外启动参数, aurora run 会注册同名的参数并透传给服务
+	Args []Arg `yaml:"args"`
+}
+
+// Load 读取项目根目录下的 aurora.yaml, 文件不存在时返回空配置
+func Load(dir string) (*Manifest, error) {
+	var (
+		m    = new(Manifest)
+		path = filepath.Join(dir, FileName)
+	)
+	m.path = path
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+	if err = yaml.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidManifest, path, err)
+	}
+	if err = m.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidManifest, path, err)
+	}
+	return m, nil
+}
+
+// Path 配置文件路径
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+func (m *Manifest) validate() error {
+	for i, arg := range m.Runtime.Args {
+		if len(arg.Type) == 0 {
+			m.Runtime.Args[i].Type = ArgString
+			arg.Type = ArgString
+		}
+		if err := arg.validate(); err != nil {
+			return fmt.Errorf("runtime.args[%d]: %v", i, err)
+		}
+	}
+	if err := m.Build.validate(); err != nil {
+		return fmt.Errorf("build: %v", err)
+	}
+	if err := m.Cron.validate(); err != nil {
+		return fmt.Errorf("cron: %v", err)
+	}
+	if err := validateTools(m.Tools); err != nil {
+		return err
+	}
+	if err := m.Config.validate(); err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+	if err := validateEnvs(m.Envs); err != nil {
+		return err
+	}
+	return nil
+}
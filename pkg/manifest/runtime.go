@@ -0,0 +1,65 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/samber/lo"
+	"strconv"
+)
+
+const (
+	ArgString  ArgType = "string"
+	ArgBool    ArgType = "bool"
+	ArgInt     ArgType = "int"
+	ArgStrings ArgType = "strings"
+)
+
+var supportedArgTypes = []ArgType{ArgString, ArgBool, ArgInt, ArgStrings}
+
+type ArgType string
+
+// Arg 服务启动参数声明
+//
+// 参数只有在命令行中显式设置时才会透传给服务, Default 仅用于帮助信息的展示
+type Arg struct {
+	Name      string  `yaml:"name"`
+	Shorthand string  `yaml:"shorthand"`
+	Type      ArgType `yaml:"type"`
+	Default   string  `yaml:"default"`
+	Usage     string  `yaml:"usage"`
+}
+
+func (t ArgType) ToString() string {
+	return string(t)
+}
+
+// IsSupported 检查是否支持的参数类型
+func (t ArgType) IsSupported() bool {
+	return lo.Contains(supportedArgTypes, t)
+}
+
+func (a Arg) validate() error {
+	if len(a.Name) == 0 {
+		return errors.New("name is required")
+	}
+	if len(a.Shorthand) > 1 {
+		return fmt.Errorf("shorthand %q must be a single character", a.Shorthand)
+	}
+	if !a.Type.IsSupported() {
+		return fmt.Errorf("unsupported type %q, expected one of %v", a.Type, supportedArgTypes)
+	}
+	if len(a.Default) == 0 {
+		return nil
+	}
+	switch a.Type {
+	case ArgBool:
+		if _, err := strconv.ParseBool(a.Default); err != nil {
+			return fmt.Errorf("invalid bool default %q", a.Default)
+		}
+	case ArgInt:
+		if _, err := strconv.Atoi(a.Default); err != nil {
+			return fmt.Errorf("invalid int default %q", a.Default)
+		}
+	}
+	return nil
+}
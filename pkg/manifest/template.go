@@ -0,0 +1,71 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+)
+
+// Template 创建项目时使用的模板, 由 aurora create 写入, 用于之后升级项目
+type Template struct {
+	Name   string `yaml:"name,omitempty"`
+	Source string `yaml:"source,omitempty"`
+	Branch string `yaml:"branch,omitempty"`
+	// Commit 创建项目时模板的提交, 本地目录和压缩包创建的项目为空
+	Commit string `yaml:"commit,omitempty"`
+	// ProjectName 创建项目时的项目名称 (内置变量 ProjectName), 升级时不依赖项目目录的名称
+	ProjectName string `yaml:"projectName,omitempty"`
+	// Variables 创建项目时模板变量 (aurora-template.yaml) 的值, 升级时使用相同的值渲染模板
+	Variables map[string]string `yaml:"variables,omitempty"`
+}
+
+// SetTemplate 写入 aurora.yaml 中的 template 配置, 保留文件中的其他内容和注释
+func SetTemplate(dir string, t Template) error {
+	var (
+		doc  yaml.Node
+		path = filepath.Join(dir, FileName)
+	)
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(content) > 0 {
+		if err = yaml.Unmarshal(content, &doc); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidManifest, path, err)
+		}
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%w: %s: the root must be a mapping", ErrInvalidManifest, path)
+	}
+	var value yaml.Node
+	if err = value.Encode(t); err != nil {
+		return err
+	}
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "template" {
+			root.Content[i+1] = &value
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "template"}, &value)
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err = encoder.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,56 @@
+package manifest
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// VersionLatest 未固定的版本, 每次 aurora init 都会重新安装
+const VersionLatest = "latest"
+
+var majorVersionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+// Tool 项目依赖的Go工具, aurora init 将其安装到项目的 ./bin/tools 目录
+type Tool struct {
+	Package string `yaml:"package"`
+	// Version 固定的版本, 如: v0.5.0
+	Version string `yaml:"version"`
+	// InModule 从 tools.go 中读取的工具, 使用 go.mod 中的版本安装
+	InModule bool `yaml:"-"`
+}
+
+// Binary 可执行文件名, 与 go install 相同: 包路径的最后一段 (忽略 /v2 等主版本后缀)
+func (t Tool) Binary() string {
+	name := path.Base(t.Package)
+	if majorVersionPattern.MatchString(name) {
+		name = path.Base(path.Dir(t.Package))
+	}
+	return name
+}
+
+// Pinned 是否固定了版本
+func (t Tool) Pinned() bool {
+	return len(t.Version) > 0 && t.Version != VersionLatest
+}
+
+func validateTools(tools []Tool) error {
+	names := make(map[string]bool, len(tools))
+	for i, t := range tools {
+		if len(t.Package) == 0 {
+			return fmt.Errorf("tools[%d]: package is required", i)
+		}
+		if strings.Contains(t.Package, "@") {
+			return fmt.Errorf("tools[%d]: set the version of %q with the version field", i, t.Package)
+		}
+		if len(t.Version) == 0 {
+			return fmt.Errorf("tools[%d]: version is required, eg: v1.2.3", i)
+		}
+		if names[t.Binary()] {
+			return fmt.Errorf("tools[%d]: duplicate tool %q", i, t.Binary())
+		}
+		names[t.Binary()] = true
+	}
+	return nil
+}
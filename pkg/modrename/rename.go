@@ -0,0 +1,315 @@
+package modrename
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidModule = errors.New("invalid module path")
+
+	// 不处理的目录
+	skipDirs = map[string]bool{".git": true, "vendor": true, "node_modules": true, "bin": true}
+
+	goPackagePattern = regexp.MustCompile(`(option\s+go_package\s*=\s*")([^"]*)(")`)
+)
+
+// Change 被修改的文件
+type Change struct {
+	// Path 相对于项目根目录的路径
+	Path string
+	// Count 替换的次数
+	Count int
+}
+
+// Report 重命名的结果
+type Report struct {
+	Changes []Change
+	// Warnings 被跳过的文件及原因
+	Warnings []string
+}
+
+// Rename 将项目中的模块路径 from 重命名为 to
+//
+// - .go 文件: 通过 go/ast 解析并只替换 import 路径
+// - .proto 文件: 替换 go_package 选项
+// - go.mod: 替换 module 指令
+// - Makefile、YAML: 替换完整的模块路径 (不会替换 "from" 作为其他单词一部分的情况)
+//
+// 跳过 .git、vendor 等目录以及二进制文件, dryRun 为 true 时只返回会被修改的文件
+//
+// 所有文件都读取并替换成功后才写入, 出错时不会留下只修改了一部分的项目
+func Rename(dir, from, to string, dryRun bool) (*Report, error) {
+	if err := checkModulePath(from); err != nil {
+		return nil, err
+	}
+	if err := checkModulePath(to); err != nil {
+		return nil, err
+	}
+	var (
+		report   = new(Report)
+		rewrites []pendingWrite
+	)
+	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && skipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rewrite := rewriterFor(rel, from, to)
+		if rewrite == nil {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isBinary(content) {
+			return nil
+		}
+		updated, count, err := rewrite(path, content)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s: %v", rel, err))
+			return nil
+		}
+		if count == 0 {
+			return nil
+		}
+		report.Changes = append(report.Changes, Change{Path: rel, Count: count})
+		rewrites = append(rewrites, pendingWrite{path: path, content: updated, perm: info.Mode().Perm()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err = writeAll(rewrites); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(report.Changes, func(i, j int) bool { return report.Changes[i].Path < report.Changes[j].Path })
+	return report, nil
+}
+
+// pendingWrite 等待写入的文件
+type pendingWrite struct {
+	path    string
+	content []byte
+	perm    fs.FileMode
+}
+
+// writeAll 先将所有文件写入同目录下的临时文件, 全部成功后再替换原文件
+func writeAll(rewrites []pendingWrite) error {
+	temps := make([]string, 0, len(rewrites))
+	for _, r := range rewrites {
+		temp, err := writeTemp(r)
+		if err != nil {
+			for _, t := range temps {
+				_ = os.Remove(t)
+			}
+			return err
+		}
+		temps = append(temps, temp)
+	}
+	for i, r := range rewrites {
+		if err := os.Rename(temps[i], r.path); err != nil {
+			for _, t := range temps[i:] {
+				_ = os.Remove(t)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTemp(r pendingWrite) (string, error) {
+	fd, err := os.CreateTemp(filepath.Dir(r.path), "."+filepath.Base(r.path)+".rename-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err = fd.Write(r.content); err == nil {
+		err = fd.Chmod(r.perm)
+	}
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(fd.Name())
+		return "", err
+	}
+	return fd.Name(), nil
+}
+
+type rewriter func(path string, content []byte) ([]byte, int, error)
+
+func rewriterFor(rel, from, to string) rewriter {
+	var (
+		name = filepath.Base(rel)
+		ext  = strings.ToLower(filepath.Ext(name))
+	)
+	switch {
+	case ext == ".go":
+		return func(path string, content []byte) ([]byte, int, error) {
+			return rewriteGoImports(path, content, from, to)
+		}
+	case ext == ".proto":
+		return func(_ string, content []byte) ([]byte, int, error) {
+			return rewriteGoPackage(content, from, to)
+		}
+	case rel == "go.mod":
+		return func(_ string, content []byte) ([]byte, int, error) {
+			return rewriteModuleDirective(content, from, to)
+		}
+	case name == "Makefile" || name == "makefile" || name == "GNUmakefile" || ext == ".mk" || ext == ".yaml" || ext == ".yml":
+		return func(_ string, content []byte) ([]byte, int, error) {
+			updated, count := replaceWord(content, from, to)
+			return updated, count, nil
+		}
+	}
+	return nil
+}
+
+// rewriteGoImports 只替换 import 路径, 保留文件的其他内容和格式
+func rewriteGoImports(path string, content []byte, from, to string) ([]byte, int, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ImportsOnly)
+	if err != nil {
+		return nil, 0, err
+	}
+	var (
+		out   bytes.Buffer
+		last  int
+		count int
+	)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+		if importPath != from && !strings.HasPrefix(importPath, from+"/") {
+			continue
+		}
+		var (
+			start = fset.Position(spec.Path.Pos()).Offset
+			end   = fset.Position(spec.Path.End()).Offset
+		)
+		out.Write(content[last:start])
+		out.WriteString(strconv.Quote(to + strings.TrimPrefix(importPath, from)))
+		last = end
+		count++
+	}
+	if count == 0 {
+		return content, 0, nil
+	}
+	out.Write(content[last:])
+	return out.Bytes(), count, nil
+}
+
+// rewriteGoPackage 替换 option go_package = "..." 中的模块路径
+func rewriteGoPackage(content []byte, from, to string) ([]byte, int, error) {
+	count := 0
+	updated := goPackagePattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		groups := goPackagePattern.FindSubmatch(match)
+		value, n := replaceWord(groups[2], from, to)
+		count += n
+		return bytes.Join([][]byte{groups[1], value, groups[3]}, nil)
+	})
+	return updated, count, nil
+}
+
+// rewriteModuleDirective 替换 go.mod 中的 module 指令
+func rewriteModuleDirective(content []byte, from, to string) ([]byte, int, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		current := strings.Trim(fields[1], "\"`")
+		if current != from {
+			return content, 0, nil
+		}
+		lines[i] = strings.Replace(line, fields[1], to, 1)
+		return []byte(strings.Join(lines, "")), 1, nil
+	}
+	return content, 0, nil
+}
+
+// replaceWord 替换完整的模块路径, 如: 替换 "app" 时不会替换 "my-app"、"app-cli" 或 "x/app"
+func replaceWord(content []byte, from, to string) ([]byte, int) {
+	var (
+		out   bytes.Buffer
+		last  int
+		count int
+	)
+	for {
+		idx := bytes.Index(content[last:], []byte(from))
+		if idx < 0 {
+			break
+		}
+		start := last + idx
+		end := start + len(from)
+		if (start == 0 || !isPathChar(content[start-1], true)) && (end == len(content) || !isPathChar(content[end], false)) {
+			out.Write(content[last:start])
+			out.WriteString(to)
+			count++
+		} else {
+			out.Write(content[last:end])
+		}
+		last = end
+	}
+	if count == 0 {
+		return content, 0
+	}
+	out.Write(content[last:])
+	return out.Bytes(), count
+}
+
+// isPathChar 是否为模块路径中的字符, 模块路径之后可以是 "/" (子包)
+func isPathChar(b byte, before bool) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	case b == '_' || b == '.' || b == '-':
+		return true
+	case b == '/':
+		return before
+	}
+	return false
+}
+
+// isBinary 包含空字符的文件视为二进制文件
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+func checkModulePath(module string) error {
+	if len(module) == 0 || strings.ContainsAny(module, " \t\r\n\"'`\\") || strings.HasPrefix(module, "/") || strings.HasSuffix(module, "/") {
+		return fmt.Errorf("%w: %q", ErrInvalidModule, module)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package modrename
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRewriteGoImports(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		count   int
+		wantErr bool
+	}{
+		{
+			name:    "single import",
+			content: "package main\n\nimport \"app/internal/x\"\n",
+			want:    "package main\n\nimport \"github.com/org/new/internal/x\"\n",
+			count:   1,
+		},
+		{
+			name:    "grouped imports with aliases and the module itself",
+			content: "package main\n\nimport (\n\t\"fmt\"\n\n\tx \"app\"\n\t_ \"app/pkg\"\n)\n",
+			want:    "package main\n\nimport (\n\t\"fmt\"\n\n\tx \"github.com/org/new\"\n\t_ \"github.com/org/new/pkg\"\n)\n",
+			count:   2,
+		},
+		{
+			name:    "imports that only share a prefix are kept",
+			content: "package main\n\nimport (\n\t\"app-cli/x\"\n\t\"my/app\"\n\t\"appx\"\n)\n",
+			want:    "package main\n\nimport (\n\t\"app-cli/x\"\n\t\"my/app\"\n\t\"appx\"\n)\n",
+		},
+		{
+			name:    "strings and comments outside imports are kept",
+			content: "package main\n\nimport \"app/x\"\n\n// app/x is imported\nvar s = \"app/x\"\n",
+			want:    "package main\n\nimport \"github.com/org/new/x\"\n\n// app/x is imported\nvar s = \"app/x\"\n",
+			count:   1,
+		},
+		{
+			name:    "raw string import",
+			content: "package main\n\nimport `app/x`\n",
+			want:    "package main\n\nimport \"github.com/org/new/x\"\n",
+			count:   1,
+		},
+		{
+			name:    "invalid go file",
+			content: "package main\n\nimport (\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, count, err := rewriteGoImports("main.go", []byte(tt.content), "app", "github.com/org/new")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rewriteGoImports() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if string(got) != tt.want || count != tt.count {
+				t.Errorf("rewriteGoImports() = %q, %d, want %q, %d", got, count, tt.want, tt.count)
+			}
+		})
+	}
+}
+
+func TestReplaceWord(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		count   int
+	}{
+		{"app", "new/app", 1},
+		{"go build app/cmd", "go build new/app/cmd", 1},
+		{"image: app:latest", "image: new/app:latest", 1},
+		{"\"app\" and 'app'", "\"new/app\" and 'new/app'", 2},
+		{"my-app", "my-app", 0},
+		{"app-cli", "app-cli", 0},
+		{"x/app", "x/app", 0},
+		{"app_test", "app_test", 0},
+		{"myapp", "myapp", 0},
+		{"app.v2", "app.v2", 0},
+		{"app my-app app", "new/app my-app new/app", 2},
+	}
+	for _, tt := range tests {
+		got, count := replaceWord([]byte(tt.content), "app", "new/app")
+		if string(got) != tt.want || count != tt.count {
+			t.Errorf("replaceWord(%q) = %q, %d, want %q, %d", tt.content, got, count, tt.want, tt.count)
+		}
+	}
+}
+
+func TestRewriteModuleDirective(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		count   int
+	}{
+		{
+			name:    "module directive",
+			content: "module app\n\ngo 1.20\n\nrequire app-lib v1.0.0\n",
+			want:    "module github.com/org/new\n\ngo 1.20\n\nrequire app-lib v1.0.0\n",
+			count:   1,
+		},
+		{
+			name:    "quoted module path",
+			content: "module \"app\"\n",
+			want:    "module github.com/org/new\n",
+			count:   1,
+		},
+		{
+			name:    "module with a comment",
+			content: "// the service\nmodule app // deprecated\n",
+			want:    "// the service\nmodule github.com/org/new // deprecated\n",
+			count:   1,
+		},
+		{
+			name:    "another module",
+			content: "module app-cli\n",
+			want:    "module app-cli\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, count, err := rewriteModuleDirective([]byte(tt.content), "app", "github.com/org/new")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want || count != tt.count {
+				t.Errorf("rewriteModuleDirective() = %q, %d, want %q, %d", got, count, tt.want, tt.count)
+			}
+		})
+	}
+}
+
+func TestRewriteGoPackage(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		count   int
+	}{
+		{`option go_package = "app/api/v1;v1";`, `option go_package = "github.com/org/new/api/v1;v1";`, 1},
+		{`option  go_package="app";`, `option  go_package="github.com/org/new";`, 1},
+		{`option go_package = "my-app/api";`, `option go_package = "my-app/api";`, 0},
+		{`option java_package = "app";`, `option java_package = "app";`, 0},
+		{"// app/api\npackage api;\n", "// app/api\npackage api;\n", 0},
+	}
+	for _, tt := range tests {
+		got, count, err := rewriteGoPackage([]byte(tt.content), "app", "github.com/org/new")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want || count != tt.count {
+			t.Errorf("rewriteGoPackage(%q) = %q, %d, want %q, %d", tt.content, got, count, tt.want, tt.count)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	files := map[string]string{
+		"go.mod":               "module app\n\ngo 1.20\n",
+		"main.go":              "package main\n\nimport _ \"app/internal\"\n",
+		"internal/internal.go": "package internal\n",
+		"api/api.proto":        "syntax = \"proto3\";\noption go_package = \"app/api;api\";\n",
+		"Makefile":             "build:\n\tgo build -o bin/app app\n",
+		"configs/config.yaml":  "env:\n  appName: app\n",
+		"README.md":            "app\n",
+		"broken.go":            "package main\n\nimport (\n",
+		"vendor/app/x.go":      "package x\n\nimport \"app/internal\"\n",
+	}
+	wantChanges := []Change{
+		{Path: "Makefile", Count: 1},
+		{Path: filepath.Join("api", "api.proto"), Count: 1},
+		{Path: filepath.Join("configs", "config.yaml"), Count: 1},
+		{Path: "go.mod", Count: 1},
+		{Path: "main.go", Count: 1},
+	}
+	for _, dryRun := range []bool{true, false} {
+		dir := t.TempDir()
+		for name, content := range files {
+			write(t, filepath.Join(dir, name), content)
+		}
+		report, err := Rename(dir, "app", "github.com/org/new", dryRun)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(report.Changes, wantChanges) {
+			t.Errorf("Rename(dryRun: %v) changes = %+v, want %+v", dryRun, report.Changes, wantChanges)
+		}
+		if len(report.Warnings) != 1 {
+			t.Errorf("Rename(dryRun: %v) warnings = %q, want the broken.go warning", dryRun, report.Warnings)
+		}
+		for name, content := range files {
+			got, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			changed := string(got) != content
+			if want := !dryRun && hasChange(wantChanges, name); changed != want {
+				t.Errorf("Rename(dryRun: %v) %s changed = %v, want %v", dryRun, name, changed, want)
+			}
+		}
+		// 不留下临时文件
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && strings.Contains(d.Name(), ".rename-") {
+				t.Errorf("the temp file %s is left", path)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRenameInvalidModule(t *testing.T) {
+	for _, module := range []string{"", "a b", "/abs", "trailing/"} {
+		if _, err := Rename(t.TempDir(), "app", module, true); !errors.Is(err, ErrInvalidModule) {
+			t.Errorf("Rename(to: %q) error = %v, want ErrInvalidModule", module, err)
+		}
+	}
+}
+
+func hasChange(changes []Change, name string) bool {
+	for _, change := range changes {
+		if change.Path == filepath.FromSlash(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
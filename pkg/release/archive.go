@@ -0,0 +1,154 @@
+package release
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatTarGz = "tar.gz"
+	FormatZip   = "zip"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported archive format")
+
+// File 打包的文件, Path 为目录时递归打包
+type File struct {
+	// Name 在压缩包中的路径
+	Name string
+	// Path 本地文件路径
+	Path string
+}
+
+// FormatFor 根据目标系统选择压缩格式, windows 使用 zip, 其他使用 tar.gz
+func FormatFor(goos string) string {
+	if goos == "windows" {
+		return FormatZip
+	}
+	return FormatTarGz
+}
+
+// Archive 将文件打包到 dst
+func Archive(dst, format string, files []File) (err error) {
+	fd, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := fd.Close(); err == nil {
+			err = e
+		}
+		if err != nil {
+			_ = os.Remove(dst)
+		}
+	}()
+
+	switch format {
+	case FormatTarGz:
+		return writeTarGz(fd, files)
+	case FormatZip:
+		return writeZip(fd, files)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
+
+func writeTarGz(w io.Writer, files []File) error {
+	var (
+		gw = gzip.NewWriter(w)
+		tw = tar.NewWriter(gw)
+	)
+	err := walkFiles(files, func(name, src string, info fs.FileInfo) error {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return copyFile(tw, src)
+	})
+	if err != nil {
+		return err
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func writeZip(w io.Writer, files []File) error {
+	zw := zip.NewWriter(w)
+	err := walkFiles(files, func(name, src string, info fs.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+		fw, err := zw.CreateHeader(header)
+		if err != nil || info.IsDir() {
+			return err
+		}
+		return copyFile(fw, src)
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// walkFiles 遍历所有需要打包的文件, 只处理普通文件和目录
+func walkFiles(files []File, fn func(name, src string, info fs.FileInfo) error) error {
+	for _, file := range files {
+		err := filepath.Walk(file.Path, func(src string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(file.Path, src)
+			if err != nil {
+				return err
+			}
+			name := path.Join(file.Name, filepath.ToSlash(rel))
+			return fn(strings.TrimPrefix(name, "/"), src, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(w io.Writer, src string) error {
+	fd, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	_, err = io.Copy(w, fd)
+	return err
+}
@@ -0,0 +1,41 @@
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumFile 校验文件名, 格式与 sha256sum 的输出一致
+const ChecksumFile = "SHA256SUMS"
+
+// WriteChecksums 计算文件的 sha256 并写入 dst
+func WriteChecksums(dst string, files []string) error {
+	var sb strings.Builder
+	for _, file := range files {
+		sum, err := sha256File(file)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(fmt.Sprintf("%s  %s\n", sum, filepath.Base(file)))
+	}
+	return os.WriteFile(dst, []byte(sb.String()), 0644)
+}
+
+func sha256File(path string) (string, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, fd); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,209 @@
+package release
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const cycloneDXSpecVersion = "1.4"
+
+var ErrNoBuildInfo = errors.New("no module information found in binary")
+
+// Module 二进制文件中记录的模块信息
+type Module struct {
+	Path    string
+	Version string
+	Sum     string
+}
+
+// BuildInfo go version -m 输出的编译信息
+type BuildInfo struct {
+	GoVersion string
+	Path      string
+	Main      Module
+	Deps      []Module
+	Settings  map[string]string
+}
+
+// ReadBuildInfo 通过 go version -m 读取二进制文件中的模块信息, 不需要网络
+func ReadBuildInfo(binary string) (*BuildInfo, error) {
+	output, err := exec.Command("go", "version", "-m", binary).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseBuildInfo(output)
+}
+
+func parseBuildInfo(output []byte) (*BuildInfo, error) {
+	var (
+		info    = &BuildInfo{Settings: make(map[string]string)}
+		scanner = bufio.NewScanner(bytes.NewReader(output))
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "\t") {
+			// 第一行: <binary>: <go version>
+			if _, goVersion, ok := strings.Cut(line, ": "); ok {
+				info.GoVersion = strings.TrimSpace(goVersion)
+			}
+			continue
+		}
+		fields := strings.Split(strings.TrimPrefix(line, "\t"), "\t")
+		switch fields[0] {
+		case "path":
+			if len(fields) > 1 {
+				info.Path = fields[1]
+			}
+		case "mod":
+			info.Main = parseModule(fields[1:])
+		case "dep":
+			info.Deps = append(info.Deps, parseModule(fields[1:]))
+		case "=>":
+			// 替换的模块以替换后的为准
+			if len(info.Deps) > 0 {
+				info.Deps[len(info.Deps)-1] = parseModule(fields[1:])
+			}
+		case "build":
+			if len(fields) > 1 {
+				key, value, _ := strings.Cut(fields[1], "=")
+				info.Settings[key] = value
+			}
+		}
+	}
+	if len(info.Path) == 0 && len(info.Main.Path) == 0 {
+		return nil, ErrNoBuildInfo
+	}
+	return info, scanner.Err()
+}
+
+func parseModule(fields []string) Module {
+	var m Module
+	if len(fields) > 0 {
+		m.Path = fields[0]
+	}
+	if len(fields) > 1 {
+		m.Version = fields[1]
+	}
+	if len(fields) > 2 {
+		m.Sum = fields[2]
+	}
+	return m
+}
+
+type cycloneDX struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cycloneDXTool    `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef     string              `json:"bom-ref"`
+	Type       string              `json:"type"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// WriteSBOM 生成 CycloneDX JSON 格式的 SBOM, mainPath 为空时使用二进制文件中记录的主模块
+func WriteSBOM(dst string, info *BuildInfo, mainPath, version, toolVersion string) error {
+	if len(mainPath) == 0 {
+		mainPath = info.Main.Path
+	}
+	main := cycloneDXComponent{
+		BOMRef:  purl(mainPath, version),
+		Type:    "application",
+		Name:    mainPath,
+		Version: version,
+		PURL:    purl(mainPath, version),
+		Properties: []cycloneDXProperty{
+			{Name: "go:version", Value: info.GoVersion},
+		},
+	}
+	for _, key := range []string{"GOOS", "GOARCH", "CGO_ENABLED", "-tags", "vcs.revision"} {
+		if value, ok := info.Settings[key]; ok {
+			main.Properties = append(main.Properties, cycloneDXProperty{Name: "go:build:" + key, Value: value})
+		}
+	}
+	bom := cycloneDX{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools:     []cycloneDXTool{{Name: "aurora", Version: toolVersion}},
+			Component: main,
+		},
+		Components:   make([]cycloneDXComponent, 0, len(info.Deps)),
+		Dependencies: []cycloneDXDependency{{Ref: main.BOMRef, DependsOn: make([]string, 0, len(info.Deps))}},
+	}
+	for _, dep := range info.Deps {
+		component := cycloneDXComponent{
+			BOMRef:  purl(dep.Path, dep.Version),
+			Type:    "library",
+			Name:    dep.Path,
+			Version: dep.Version,
+			PURL:    purl(dep.Path, dep.Version),
+		}
+		if len(dep.Sum) > 0 {
+			component.Properties = append(component.Properties, cycloneDXProperty{Name: "go:module:sum", Value: dep.Sum})
+		}
+		bom.Components = append(bom.Components, component)
+		bom.Dependencies[0].DependsOn = append(bom.Dependencies[0].DependsOn, component.BOMRef)
+	}
+	content, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, append(content, '\n'), 0644)
+}
+
+// purl Go模块的 package url, 如: pkg:golang/github.com/spf13/cobra@v1.7.0
+func purl(path, version string) string {
+	if len(version) == 0 || version == "(devel)" {
+		return fmt.Sprintf("pkg:golang/%s", path)
+	}
+	return fmt.Sprintf("pkg:golang/%s@%s", path, version)
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
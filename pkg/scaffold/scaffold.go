@@ -0,0 +1,139 @@
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// GeneratedMarker 生成文件的标记, 没有该标记的已存在文件不会被覆盖
+	GeneratedMarker = "Code generated by aurora"
+
+	regionBegin = "aurora:user-begin"
+	regionEnd   = "aurora:user-end"
+)
+
+const (
+	Created   Result = "created"
+	Updated   Result = "updated"
+	Unchanged Result = "unchanged"
+)
+
+var (
+	ErrNotGenerated   = errors.New("file exists and was not generated by aurora")
+	ErrInvalidRegions = errors.New("invalid user regions")
+)
+
+// Result 写入文件的结果
+type Result string
+
+// Write 写入生成的文件, 保留已有文件中用户编辑区域的内容, 内容没有变化时不写入
+//
+// 用户编辑区域以包含 "aurora:user-begin <name>" 和 "aurora:user-end <name>" 的行为边界
+func Write(path string, content []byte, force bool) (Result, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	result := Created
+	if err == nil {
+		result = Updated
+		if !bytes.Contains(existing, []byte(GeneratedMarker)) && !force {
+			return "", fmt.Errorf("%w: %s", ErrNotGenerated, path)
+		}
+		if content, err = MergeRegions(content, existing); err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+		if bytes.Equal(content, existing) {
+			return Unchanged, nil
+		}
+	}
+	if err = os.MkdirAll(filepath.Dir(path), fs.ModePerm); err != nil {
+		return "", err
+	}
+	return result, os.WriteFile(path, content, 0644)
+}
+
+// MergeRegions 用 existing 中同名用户编辑区域的内容替换 generated 中的内容
+func MergeRegions(generated, existing []byte) ([]byte, error) {
+	regions, err := parseRegions(existing)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out     bytes.Buffer
+		current string
+		lines   = strings.SplitAfter(string(generated), "\n")
+	)
+	for _, line := range lines {
+		if name, ok := regionName(line, regionBegin); ok {
+			out.WriteString(line)
+			current = name
+			if body, ok := regions[name]; ok {
+				out.WriteString(body)
+			}
+			continue
+		}
+		if name, ok := regionName(line, regionEnd); ok && name == current {
+			current = ""
+			out.WriteString(line)
+			continue
+		}
+		// 已有文件中存在的区域使用已有的内容
+		if _, ok := regions[current]; ok && len(current) > 0 {
+			continue
+		}
+		out.WriteString(line)
+	}
+	return out.Bytes(), nil
+}
+
+func parseRegions(content []byte) (map[string]string, error) {
+	var (
+		regions = make(map[string]string)
+		current string
+		body    strings.Builder
+	)
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if name, ok := regionName(line, regionBegin); ok {
+			if len(current) > 0 {
+				return nil, fmt.Errorf("%w: region %q is not closed", ErrInvalidRegions, current)
+			}
+			current = name
+			body.Reset()
+			continue
+		}
+		if name, ok := regionName(line, regionEnd); ok {
+			if name != current {
+				return nil, fmt.Errorf("%w: unexpected end of region %q", ErrInvalidRegions, name)
+			}
+			regions[current] = body.String()
+			current = ""
+			continue
+		}
+		if len(current) > 0 {
+			body.WriteString(line)
+		}
+	}
+	if len(current) > 0 {
+		return nil, fmt.Errorf("%w: region %q is not closed", ErrInvalidRegions, current)
+	}
+	return regions, nil
+}
+
+func regionName(line, marker string) (string, bool) {
+	idx := strings.Index(line, marker)
+	if idx < 0 {
+		return "", false
+	}
+	fields := strings.Fields(line[idx+len(marker):])
+	if len(fields) == 0 {
+		return "", false
+	}
+	return fields[0], true
+}
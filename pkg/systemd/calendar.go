@@ -0,0 +1,198 @@
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupportedSchedule = errors.New("unsupported cron schedule")
+
+// cronField cron表达式中每一段的取值范围
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	months = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	weekdays = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+	weekdayNames = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+	fieldMinute  = cronField{name: "minute", min: 0, max: 59}
+	fieldHour    = cronField{name: "hour", min: 0, max: 23}
+	fieldDay     = cronField{name: "day of month", min: 1, max: 31}
+	fieldMonth   = cronField{name: "month", min: 1, max: 12, names: months}
+	fieldWeekday = cronField{name: "day of week", min: 0, max: 7, names: weekdays}
+)
+
+// OnCalendar 将5段式cron表达式转换为systemd timer的 OnCalendar 表达式
+//
+// 如: "*/5 * * * *" -> "*-*-* *:0/5:00", "30 2 * * 1-5" -> "Mon..Fri *-*-* 02:30:00"
+//
+// cron在同时指定"日"和"星期"时任意一个满足即执行, systemd要求同时满足, 这种表达式不支持转换
+func OnCalendar(schedule string) (string, error) {
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return "", fmt.Errorf("%w: %q must have 5 fields", ErrUnsupportedSchedule, schedule)
+	}
+	if fields[2] != "*" && fields[4] != "*" {
+		return "", fmt.Errorf("%w: %q restricts both day of month and day of week", ErrUnsupportedSchedule, schedule)
+	}
+	var (
+		parts = make([]string, 5)
+		err   error
+	)
+	for i, field := range []cronField{fieldMinute, fieldHour, fieldDay, fieldMonth} {
+		if parts[i], err = field.convert(fields[i], 2); err != nil {
+			return "", fmt.Errorf("%w: %q: %v", ErrUnsupportedSchedule, schedule, err)
+		}
+	}
+	if parts[4], err = convertWeekday(fields[4]); err != nil {
+		return "", fmt.Errorf("%w: %q: %v", ErrUnsupportedSchedule, schedule, err)
+	}
+	calendar := fmt.Sprintf("*-%s-%s %s:%s:00", parts[3], parts[2], parts[1], parts[0])
+	if parts[4] != "*" {
+		calendar = parts[4] + " " + calendar
+	}
+	return calendar, nil
+}
+
+// convert 转换一段cron表达式, 逗号分隔的每一项单独转换
+func (f cronField) convert(expr string, width int) (string, error) {
+	items := strings.Split(expr, ",")
+	for i, item := range items {
+		converted, err := f.convertItem(item, width)
+		if err != nil {
+			return "", err
+		}
+		items[i] = converted
+	}
+	return strings.Join(items, ","), nil
+}
+
+func (f cronField) convertItem(item string, width int) (string, error) {
+	rangeExpr, step, hasStep := strings.Cut(item, "/")
+	if hasStep {
+		n, err := strconv.Atoi(step)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid step %q in %s", step, f.name)
+		}
+		step = strconv.Itoa(n)
+	}
+	switch {
+	case rangeExpr == "*":
+		if hasStep {
+			// systemd 的 "起始/步长" 与 cron 的 "*/步长" 等价
+			return f.format(f.min, width) + "/" + step, nil
+		}
+		return "*", nil
+	case strings.Contains(rangeExpr, "-"):
+		from, to, _ := strings.Cut(rangeExpr, "-")
+		start, err := f.value(from)
+		if err != nil {
+			return "", err
+		}
+		end, err := f.value(to)
+		if err != nil {
+			return "", err
+		}
+		if start > end {
+			return "", fmt.Errorf("invalid range %q in %s", rangeExpr, f.name)
+		}
+		if !hasStep {
+			return f.format(start, width) + ".." + f.format(end, width), nil
+		}
+		// systemd 不支持带步长的范围, 展开为列表
+		n, _ := strconv.Atoi(step)
+		values := make([]string, 0, (end-start)/n+1)
+		for v := start; v <= end; v += n {
+			values = append(values, f.format(v, width))
+		}
+		return strings.Join(values, ","), nil
+	default:
+		v, err := f.value(rangeExpr)
+		if err != nil {
+			return "", err
+		}
+		if hasStep {
+			return f.format(v, width) + "/" + step, nil
+		}
+		return f.format(v, width), nil
+	}
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.name, s)
+	}
+	return v, nil
+}
+
+func (f cronField) format(v, width int) string {
+	return fmt.Sprintf("%0*d", width, v)
+}
+
+// convertWeekday 转换"星期", systemd 使用 Mon..Sun 的英文缩写
+func convertWeekday(expr string) (string, error) {
+	if expr == "*" {
+		return "*", nil
+	}
+	items := strings.Split(expr, ",")
+	for i, item := range items {
+		rangeExpr, step, hasStep := strings.Cut(item, "/")
+		start, end := fieldWeekday.min, fieldWeekday.max-1
+		if rangeExpr != "*" {
+			from, to, isRange := strings.Cut(rangeExpr, "-")
+			var err error
+			if start, err = fieldWeekday.value(from); err != nil {
+				return "", err
+			}
+			end = start
+			if isRange {
+				if end, err = fieldWeekday.value(to); err != nil {
+					return "", err
+				}
+			}
+			if start > end {
+				return "", fmt.Errorf("invalid range %q in %s", rangeExpr, fieldWeekday.name)
+			}
+		}
+		if !hasStep {
+			if start == end {
+				items[i] = weekdayNames[start]
+			} else {
+				items[i] = weekdayNames[start] + ".." + weekdayNames[end]
+			}
+			// systemd 中 "Sun" 是一周的最后一天, "Sun..Sat" 这样的范围需要拆分
+			if start == 0 && end > 0 {
+				items[i] = "Sun," + weekdayNames[1] + ".." + weekdayNames[end]
+				if end == 1 {
+					items[i] = "Sun,Mon"
+				}
+			}
+			continue
+		}
+		n, err := strconv.Atoi(step)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid step %q in %s", step, fieldWeekday.name)
+		}
+		names := make([]string, 0, 7)
+		for v := start; v <= end; v += n {
+			names = append(names, weekdayNames[v])
+		}
+		items[i] = strings.Join(names, ",")
+	}
+	return strings.Join(items, ","), nil
+}
@@ -0,0 +1,208 @@
+package templates
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RefreshTimeout 更新已有缓存的超时时间, 超时后使用已有的缓存; 第一次克隆没有可以回退的缓存, 不限制时间
+const RefreshTimeout = 30 * time.Second
+
+var (
+	ErrNotCached = errors.New("template is not cached")
+
+	cacheNameCleaner = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// Cache git模板仓库的本地镜像缓存 (git clone --mirror)
+type Cache struct {
+	dir string
+}
+
+// Result 拉取模板的结果
+type Result struct {
+	Kind Kind
+	// Commit 模板的提交, 本地目录和压缩包为空
+	Commit string
+	// Stale 刷新失败, 使用的是已有的缓存
+	Stale error
+}
+
+// DefaultCacheDir 默认的缓存目录, 优先使用 $XDG_CACHE_HOME/aurora, 否则为 ~/.cache/aurora
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); len(dir) > 0 {
+		return filepath.Join(dir, "aurora"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".cache", "aurora"), nil
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// MirrorPath 模板仓库镜像的路径
+func (c *Cache) MirrorPath(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	name := strings.TrimSuffix(filepath.Base(strings.TrimRight(source, "/")), ".git")
+	name = strings.Trim(cacheNameCleaner.ReplaceAllString(name, "-"), "-")
+	return filepath.Join(c.dir, "templates", fmt.Sprintf("%s-%s.git", name, hex.EncodeToString(sum[:])[:12]))
+}
+
+// Cached 模板仓库是否已缓存
+func (c *Cache) Cached(source string) bool {
+	_, err := os.Stat(filepath.Join(c.MirrorPath(source), "HEAD"))
+	return err == nil
+}
+
+// Refresh 创建或更新模板仓库的镜像
+func (c *Cache) Refresh(source string) error {
+	mirror := c.MirrorPath(source)
+	if c.Cached(source) {
+		ctx, cancel := context.WithTimeout(context.Background(), RefreshTimeout)
+		defer cancel()
+		return git(ctx, mirror, "remote", "update", "--prune")
+	}
+	if err := os.MkdirAll(filepath.Dir(mirror), fs.ModePerm); err != nil {
+		return err
+	}
+	// 先克隆到临时目录, 避免中断后留下不完整的镜像
+	tmp := mirror + ".tmp"
+	_ = os.RemoveAll(tmp)
+	if err := git(context.Background(), "", "clone", "--mirror", "--quiet", source, tmp); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	return os.Rename(tmp, mirror)
+}
+
+// Fetch 将模板拉取到 dst 目录并返回模板的提交
+//
+// git仓库从本地镜像克隆: offline 为 false 时先尝试刷新镜像, 刷新失败时使用已有的缓存;
+// offline 为 true 时只使用缓存. 本地目录和压缩包直接复制
+func (c *Cache) Fetch(source, branch, dst string, offline bool) (Result, error) {
+	result := Result{Kind: KindOf(source)}
+	if result.Kind != KindGit {
+		return result, Fetch(source, branch, dst)
+	}
+	if !offline {
+		if err := c.Refresh(source); err != nil {
+			if !c.Cached(source) {
+				return result, err
+			}
+			result.Stale = err
+		}
+	} else if !c.Cached(source) {
+		return result, fmt.Errorf("%w: %s, run without --offline once to cache it", ErrNotCached, source)
+	}
+	mirror, err := filepath.Abs(c.MirrorPath(source))
+	if err != nil {
+		return result, err
+	}
+	if err = Fetch("file://"+filepath.ToSlash(mirror), branch, dst); err != nil {
+		return result, err
+	}
+	result.Commit, err = Commit(dst)
+	return result, err
+}
+
+// Export 将模板仓库镜像中 rev (分支、标签或提交) 对应的文件导出到 dst, 返回完整的提交
+func (c *Cache) Export(source, rev, dst string) (string, error) {
+	if !c.Cached(source) {
+		return "", fmt.Errorf("%w: %s", ErrNotCached, source)
+	}
+	repo, err := gogit.PlainOpen(c.MirrorPath(source))
+	if err != nil {
+		return "", err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", err
+	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		target := filepath.Join(dst, filepath.FromSlash(f.Name))
+		if err := checkParents(dst, target); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), fs.ModePerm); err != nil {
+			return err
+		}
+		switch f.Mode {
+		case filemode.Symlink:
+			link, err := f.Contents()
+			if err != nil {
+				return err
+			}
+			return symlink(dst, target, link)
+		case filemode.Regular, filemode.Executable, filemode.Deprecated:
+			perm := fs.FileMode(0644)
+			if f.Mode == filemode.Executable {
+				perm = 0755
+			}
+			r, err := f.Reader()
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			return writeFile(target, r, perm)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if err = checkLinks(dst); err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
+
+// Commit 目录对应的git仓库当前的提交
+func Commit(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func git(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// 禁止git在没有终端时等待输入账号密码
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("git %s: %w", args[0], ctx.Err())
+		}
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
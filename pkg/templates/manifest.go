@@ -0,0 +1,368 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	// ManifestFile 模板的配置文件, 创建项目后删除
+	ManifestFile = "aurora-template.yaml"
+	// TemplateSuffix 以该后缀结尾的文件会被渲染, 并去掉后缀
+	TemplateSuffix = ".tmpl"
+)
+
+const (
+	VarString VarType = "string"
+	VarBool   VarType = "bool"
+	VarInt    VarType = "int"
+	VarSelect VarType = "select"
+)
+
+var (
+	ErrInvalidManifest = errors.New("invalid " + ManifestFile)
+
+	varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// VarType 变量的类型
+type VarType string
+
+// Manifest 模板中的 aurora-template.yaml
+type Manifest struct {
+	// Variables 创建项目时询问的变量, 按顺序询问
+	Variables []Variable `yaml:"variables"`
+	// Files 根据条件决定是否保留的文件或目录
+	Files []ConditionalFile `yaml:"files"`
+	// Render 需要渲染的文件 (相对于项目根目录的glob), 以 .tmpl 结尾的文件总是会被渲染
+	Render []string `yaml:"render"`
+	// Hooks 项目创建完成后在项目目录中按顺序执行的命令, 如: wire、go generate
+	Hooks []Hook `yaml:"hooks"`
+}
+
+// Variable 模板变量, 在模板中通过 {{.Name}} 引用
+type Variable struct {
+	Name   string  `yaml:"name"`
+	Prompt string  `yaml:"prompt"`
+	Help   string  `yaml:"help"`
+	Type   VarType `yaml:"type"`
+	// Default 默认值, 可以引用之前的变量以及内置变量 ProjectName、Module, 如: "{{.ProjectName}}-svc"
+	Default  string   `yaml:"default"`
+	Options  []string `yaml:"options"`
+	Required bool     `yaml:"required"`
+}
+
+// ConditionalFile when 为模板表达式 (如: ".WithMQ"、`eq .DB "mysql"`), 结果为假时删除 Path
+type ConditionalFile struct {
+	Path string `yaml:"path"`
+	When string `yaml:"when"`
+}
+
+// Hook 创建项目后执行的命令, Run 中的每个参数都会被渲染, 如: ["go", "run", "./cmd/gen", "{{.Module}}"]
+type Hook struct {
+	Name string   `yaml:"name"`
+	Run  []string `yaml:"run"`
+	// When 为空或结果为真时执行
+	When string `yaml:"when"`
+	// Optional 执行失败时只提示而不中断创建
+	Optional bool `yaml:"optional"`
+}
+
+// LoadManifest 读取模板目录下的 aurora-template.yaml, 文件不存在时返回 nil
+func LoadManifest(dir string) (*Manifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := new(Manifest)
+	if err = yaml.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	if err = m.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	return m, nil
+}
+
+func (m *Manifest) validate() error {
+	names := make(map[string]bool, len(m.Variables))
+	for i := range m.Variables {
+		v := &m.Variables[i]
+		if !varNamePattern.MatchString(v.Name) {
+			return fmt.Errorf("variables[%d]: invalid name %q", i, v.Name)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("variables[%d]: duplicate variable %q", i, v.Name)
+		}
+		names[v.Name] = true
+		if len(v.Type) == 0 {
+			v.Type = VarString
+		}
+		switch v.Type {
+		case VarString, VarBool, VarInt:
+		case VarSelect:
+			if len(v.Options) == 0 {
+				return fmt.Errorf("variables[%d]: options are required for type %q", i, v.Type)
+			}
+		default:
+			return fmt.Errorf("variables[%d]: unsupported type %q", i, v.Type)
+		}
+		// 引用了其他变量的默认值在渲染后校验
+		if len(v.Default) > 0 && !strings.Contains(v.Default, "{{") {
+			if _, err := v.Parse(v.Default); err != nil {
+				return fmt.Errorf("variables[%d]: invalid default: %v", i, err)
+			}
+		}
+	}
+	for i, f := range m.Files {
+		if len(f.Path) == 0 || len(f.When) == 0 {
+			return fmt.Errorf("files[%d]: path and when are required", i)
+		}
+		if _, err := parseCondition(f.When); err != nil {
+			return fmt.Errorf("files[%d]: %v", i, err)
+		}
+	}
+	for i, pattern := range m.Render {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("render[%d]: %v", i, err)
+		}
+	}
+	for i := range m.Hooks {
+		h := &m.Hooks[i]
+		if len(h.Run) == 0 || len(h.Run[0]) == 0 {
+			return fmt.Errorf("hooks[%d]: run is required", i)
+		}
+		if len(h.When) > 0 {
+			if _, err := parseCondition(h.When); err != nil {
+				return fmt.Errorf("hooks[%d]: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveHooks 返回需要执行的钩子, 跳过条件不满足的钩子并渲染命令参数
+func (m *Manifest) ResolveHooks(values map[string]interface{}) ([]Hook, error) {
+	hooks := make([]Hook, 0, len(m.Hooks))
+	for _, h := range m.Hooks {
+		if len(h.When) > 0 {
+			ok, err := evalCondition(h.When, values)
+			if err != nil {
+				return nil, fmt.Errorf("hook %s: %w", h.Name, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		args := make([]string, len(h.Run))
+		for i, arg := range h.Run {
+			rendered, err := RenderString(arg, values)
+			if err != nil {
+				return nil, fmt.Errorf("hooks: %s: %w", arg, err)
+			}
+			args[i] = rendered
+		}
+		h.Run = args
+		if len(h.Name) == 0 {
+			h.Name = strings.Join(h.Run, " ")
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, nil
+}
+
+// Parse 将输入转换为变量类型对应的值
+func (v Variable) Parse(s string) (interface{}, error) {
+	switch v.Type {
+	case VarBool:
+		return strconv.ParseBool(s)
+	case VarInt:
+		return strconv.Atoi(s)
+	case VarSelect:
+		for _, option := range v.Options {
+			if option == s {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not one of %v", s, v.Options)
+	default:
+		if v.Required && len(s) == 0 {
+			return nil, fmt.Errorf("%s is required", v.Name)
+		}
+		return s, nil
+	}
+}
+
+// Values 非交互地计算所有变量的值, answers 中没有的变量使用默认值
+func (m *Manifest) Values(builtin map[string]interface{}, answers map[string]string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(builtin)+len(m.Variables))
+	for k, v := range builtin {
+		values[k] = v
+	}
+	for _, v := range m.Variables {
+		answer, ok := answers[v.Name]
+		if !ok {
+			var err error
+			if answer, err = v.DefaultValue(values); err != nil {
+				return nil, fmt.Errorf("the default value of variable %s: %w", v.Name, err)
+			}
+		}
+		value, err := v.Parse(answer)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", v.Name, err)
+		}
+		values[v.Name] = value
+	}
+	return values, nil
+}
+
+// DefaultValue 渲染默认值
+func (v Variable) DefaultValue(values map[string]interface{}) (string, error) {
+	if !strings.Contains(v.Default, "{{") {
+		return v.Default, nil
+	}
+	return RenderString(v.Default, values)
+}
+
+// RenderString 渲染字符串模板, 引用不存在的变量时返回错误
+func RenderString(text string, values map[string]interface{}) (string, error) {
+	tpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, values); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Apply 在项目目录中应用模板: 删除条件不满足的文件, 渲染模板文件, 最后删除 aurora-template.yaml
+//
+// 返回被删除和被渲染的文件
+func (m *Manifest) Apply(dir string, values map[string]interface{}) (removed, rendered []string, err error) {
+	for _, f := range m.Files {
+		keep, err := evalCondition(f.When, values)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		if keep {
+			continue
+		}
+		target, err := projectPath(dir, f.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = os.RemoveAll(target); err != nil {
+			return nil, nil, err
+		}
+		removed = append(removed, f.Path)
+	}
+	err = filepath.Walk(dir, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !info.Mode().IsRegular() || rel == ManifestFile || !m.shouldRender(rel) {
+			return nil
+		}
+		if err = renderFile(p, info.Mode().Perm(), values); err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		rendered = append(rendered, strings.TrimSuffix(rel, TemplateSuffix))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = os.Remove(filepath.Join(dir, ManifestFile)); err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	return removed, rendered, nil
+}
+
+func (m *Manifest) shouldRender(rel string) bool {
+	if strings.HasSuffix(rel, TemplateSuffix) {
+		return true
+	}
+	for _, pattern := range m.Render {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		// 不包含目录的 glob 匹配任意目录下的文件
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renderFile 渲染文件, 以 .tmpl 结尾的文件去掉后缀
+func renderFile(p string, perm fs.FileMode, values map[string]interface{}) error {
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	rendered, err := RenderString(string(content), values)
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSuffix(p, TemplateSuffix)
+	if err = os.WriteFile(target, []byte(rendered), perm); err != nil {
+		return err
+	}
+	if target != p {
+		return os.Remove(p)
+	}
+	return nil
+}
+
+func parseCondition(when string) (*template.Template, error) {
+	return template.New("").Option("missingkey=error").Parse("{{if " + when + "}}true{{end}}")
+}
+
+func evalCondition(when string, values map[string]interface{}) (bool, error) {
+	tpl, err := parseCondition(when)
+	if err != nil {
+		return false, err
+	}
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, values); err != nil {
+		return false, err
+	}
+	return buf.String() == "true", nil
+}
+
+// projectPath 转换为项目目录下的路径, 不允许指向项目目录之外
+func projectPath(dir, rel string) (string, error) {
+	clean := path.Clean(filepath.ToSlash(rel))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || clean == "." {
+		return "", fmt.Errorf("%w: path %q must be inside the project", ErrInvalidManifest, rel)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
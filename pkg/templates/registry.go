@@ -0,0 +1,146 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// RegistryFile 用户级模板注册表的文件名, 位于 ~/.aurora 目录下
+	RegistryFile = "templates.yaml"
+	// DefaultName 内置的 prepare2go 模板
+	DefaultName = "prepare2go"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrTemplateExists   = errors.New("template already exists")
+	ErrBuiltinTemplate  = errors.New("built-in template cannot be changed")
+)
+
+// Template 注册表中的模板
+type Template struct {
+	Name string `yaml:"name"`
+	// Source git仓库地址、本地目录或 .tar.gz 压缩包
+	Source string `yaml:"source"`
+	// Branch git仓库的分支, 为空时使用 aurora create 的默认分支
+	Branch      string `yaml:"branch,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	builtin     bool
+}
+
+// Builtin 是否为内置模板
+func (t Template) Builtin() bool {
+	return t.builtin
+}
+
+// Registry 用户级模板注册表 (~/.aurora/templates.yaml)
+type Registry struct {
+	Templates []Template `yaml:"templates"`
+
+	path    string
+	builtin Template
+}
+
+// DefaultRegistryPath 默认的注册表路径
+func DefaultRegistryPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".aurora", RegistryFile), nil
+}
+
+// LoadRegistry 读取注册表, 文件不存在时返回只包含内置模板的注册表
+func LoadRegistry(path string, builtin Template) (*Registry, error) {
+	builtin.builtin = true
+	r := &Registry{path: path, builtin: builtin}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(content, r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// List 所有模板, 内置模板排在最前面
+func (r *Registry) List() []Template {
+	list := make([]Template, 0, len(r.Templates)+1)
+	list = append(list, r.builtin)
+	sorted := append([]Template{}, r.Templates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return append(list, sorted...)
+}
+
+// Get 按名称查找模板
+func (r *Registry) Get(name string) (Template, bool) {
+	for _, t := range r.List() {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// Add 添加模板, replace 为 true 时覆盖同名模板
+func (r *Registry) Add(t Template, replace bool) error {
+	if len(t.Name) == 0 || len(t.Source) == 0 {
+		return errors.New("the name and source of the template are required")
+	}
+	if t.Name == r.builtin.Name {
+		return fmt.Errorf("%w: %s", ErrBuiltinTemplate, t.Name)
+	}
+	for i, existing := range r.Templates {
+		if existing.Name != t.Name {
+			continue
+		}
+		if !replace {
+			return fmt.Errorf("%w: %s", ErrTemplateExists, t.Name)
+		}
+		r.Templates[i] = t
+		return nil
+	}
+	r.Templates = append(r.Templates, t)
+	return nil
+}
+
+// Remove 删除模板
+func (r *Registry) Remove(name string) error {
+	if name == r.builtin.Name {
+		return fmt.Errorf("%w: %s", ErrBuiltinTemplate, name)
+	}
+	for i, t := range r.Templates {
+		if t.Name == name {
+			r.Templates = append(r.Templates[:i], r.Templates[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+}
+
+// Save 写入注册表文件
+func (r *Registry) Save() error {
+	content, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.path), fs.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, content, 0644)
+}
+
+// Path 注册表文件的路径
+func (r *Registry) Path() string {
+	return r.path
+}
@@ -0,0 +1,309 @@
+package templates
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Kind 模板来源的类型
+type Kind string
+
+const (
+	KindGit     Kind = "git"
+	KindDir     Kind = "dir"
+	KindArchive Kind = "archive"
+)
+
+var (
+	ErrUnsafeArchive = errors.New("unsafe path in archive")
+	ErrUnsafeLink    = errors.New("unsafe symlink in template")
+)
+
+// KindOf 判断模板来源的类型, 本地目录和 .tar.gz 压缩包不需要网络
+func KindOf(source string) Kind {
+	if strings.HasSuffix(source, ".tar.gz") || strings.HasSuffix(source, ".tgz") {
+		if _, err := os.Stat(source); err == nil {
+			return KindArchive
+		}
+	}
+	if info, err := os.Stat(source); err == nil && info.IsDir() && !isBareRepo(source) {
+		return KindDir
+	}
+	return KindGit
+}
+
+// Fetch 将模板拉取到 dst 目录, git仓库只克隆 branch 分支的最新提交
+func Fetch(source, branch, dst string) error {
+	switch KindOf(source) {
+	case KindArchive:
+		if err := extractArchive(source, dst); err != nil {
+			return err
+		}
+		return checkLinks(dst)
+	case KindDir:
+		if err := copyDir(source, dst); err != nil {
+			return err
+		}
+		return checkLinks(dst)
+	default:
+		args := []string{"clone", source, dst, "--depth", "1", "--single-branch", "--no-tags"}
+		if len(branch) > 0 {
+			args = append(args, "-b", branch)
+		}
+		cmd := exec.Command("git", args...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+		}
+		return checkLinks(dst)
+	}
+}
+
+// isBareRepo 本地的裸仓库按git仓库处理
+func isBareRepo(dir string) bool {
+	if strings.HasSuffix(dir, ".git") {
+		return true
+	}
+	head, err := os.Stat(filepath.Join(dir, "HEAD"))
+	if err != nil || head.IsDir() {
+		return false
+	}
+	objects, err := os.Stat(filepath.Join(dir, "objects"))
+	return err == nil && objects.IsDir()
+}
+
+// copyDir 复制本地目录, 忽略 .git 目录
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return symlink(dst, target, link)
+		case info.Mode().IsRegular():
+			return copyFile(p, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dst, in, perm)
+}
+
+func writeFile(dst string, r io.Reader, perm fs.FileMode) (err error) {
+	if err = os.MkdirAll(filepath.Dir(dst), fs.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := out.Close(); err == nil {
+			err = e
+		}
+	}()
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// extractArchive 解压 .tar.gz 压缩包, 忽略 .git 目录; 所有文件在同一个顶层目录下时 (如: GitHub 下载的源码包) 去掉该目录
+func extractArchive(src, dst string) error {
+	prefix, err := archivePrefix(src)
+	if err != nil {
+		return err
+	}
+	return walkArchive(src, func(header *tar.Header, r io.Reader) error {
+		name := path.Clean(header.Name)
+		if len(prefix) > 0 && name+"/" == prefix {
+			return nil
+		}
+		name = strings.TrimPrefix(name, prefix)
+		if name == "." {
+			return nil
+		}
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("%w: %s", ErrUnsafeArchive, header.Name)
+		}
+		// 与本地目录一致, 忽略 .git 目录
+		if name == ".git" || strings.HasPrefix(name, ".git/") {
+			return nil
+		}
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		// 不允许通过前面解压的符号链接写到 dst 之外
+		if err := checkParents(dst, target); err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			return os.MkdirAll(target, header.FileInfo().Mode().Perm()|0700)
+		case tar.TypeReg:
+			return writeFile(target, r, header.FileInfo().Mode().Perm())
+		case tar.TypeSymlink:
+			return symlink(dst, target, header.Linkname)
+		}
+		return nil
+	})
+}
+
+// symlink 在模板目录 root 中创建符号链接, 链接只能指向 root 中的相对路径
+func symlink(root, target, link string) error {
+	if filepath.IsAbs(link) || path.IsAbs(link) || !within(root, filepath.Join(filepath.Dir(target), filepath.FromSlash(link))) {
+		return fmt.Errorf("%w: %s -> %s", ErrUnsafeLink, relPath(root, target), link)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), fs.ModePerm); err != nil {
+		return err
+	}
+	return os.Symlink(link, target)
+}
+
+// checkParents target 在 root 中的上级目录都不能是符号链接
+func checkParents(root, target string) error {
+	rel, err := filepath.Rel(root, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+	dir := root
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, name)
+		info, err := os.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s is written through the symlink %s", ErrUnsafeLink, relPath(root, target), relPath(root, dir))
+		}
+	}
+	return nil
+}
+
+// checkLinks 模板中所有符号链接解析后都必须在 root 中, 链接可能经过其他链接 (如: a -> b/.., b -> .)
+func checkLinks(root string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(root, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		link, err := os.Readlink(p)
+		if err != nil {
+			return err
+		}
+		if filepath.IsAbs(link) {
+			return fmt.Errorf("%w: %s -> %s", ErrUnsafeLink, relPath(root, p), link)
+		}
+		// 无法解析的链接 (目标不存在) 不会被读写, 只检查字面路径
+		base := realRoot
+		resolved, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			base, resolved = root, filepath.Join(filepath.Dir(p), link)
+		}
+		if !within(base, resolved) {
+			return fmt.Errorf("%w: %s -> %s", ErrUnsafeLink, relPath(root, p), link)
+		}
+		return nil
+	})
+}
+
+// within path 是否为 root 或在 root 中
+func within(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func relPath(root, p string) string {
+	if rel, err := filepath.Rel(root, p); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return p
+}
+
+// archivePrefix 所有文件共同的顶层目录, 没有时返回空字符串
+func archivePrefix(src string) (string, error) {
+	var (
+		top    string
+		shared = true
+	)
+	err := walkArchive(src, func(header *tar.Header, _ io.Reader) error {
+		first, _, nested := strings.Cut(path.Clean(header.Name), "/")
+		if !nested && header.Typeflag != tar.TypeDir {
+			shared = false
+		}
+		if len(top) == 0 {
+			top = first
+		} else if top != first {
+			shared = false
+		}
+		return nil
+	})
+	if err != nil || !shared || len(top) == 0 {
+		return "", err
+	}
+	return top + "/", nil
+}
+
+func walkArchive(src string, fn func(header *tar.Header, r io.Reader) error) error {
+	fd, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	gr, err := gzip.NewReader(fd)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		if err = fn(header, tr); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"github.com/stubborn-gaga-0805/aurora/helpers"
+	"github.com/stubborn-gaga-0805/aurora/pkg/manifest"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Dir 工具的安装目录, 相对于项目根目录
+const Dir = "bin/tools"
+
+const (
+	StatusOK       Status = "ok"
+	StatusMissing  Status = "missing"
+	StatusDrift    Status = "drift"
+	StatusUnpinned Status = "unpinned"
+)
+
+var (
+	ErrNotRequired = errors.New("the module of the tool is not required in go.mod")
+
+	// toolsGoFiles tools.go 的位置, 相对于项目根目录
+	toolsGoFiles = []string{"tools.go", filepath.Join("tools", "tools.go")}
+)
+
+// Status 已安装的工具与声明的版本是否一致
+type Status string
+
+// Check 工具的检查结果
+type Check struct {
+	Tool      manifest.Tool
+	Installed string
+	Status    Status
+}
+
+// BinPath 工具可执行文件的路径
+func BinPath(projectDir string, t manifest.Tool) string {
+	return binPath(projectDir, t.Binary())
+}
+
+func binPath(projectDir, name string) string {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(projectDir, filepath.FromSlash(Dir), name)
+}
+
+// Lookup 优先使用项目 ./bin/tools 中的工具, 不存在时使用 PATH 中的同名命令
+func Lookup(projectDir, name string) string {
+	p := binPath(projectDir, name)
+	if _, err := os.Stat(p); err == nil {
+		return p
+	}
+	return name
+}
+
+// PrependPath 项目的 ./bin/tools 存在时将其添加到 PATH 的最前面, 使子进程调用的工具 (如: protoc 插件、wire) 优先使用项目中固定版本的工具
+func PrependPath(projectDir, path string) string {
+	binDir, err := filepath.Abs(filepath.Join(projectDir, filepath.FromSlash(Dir)))
+	if err != nil {
+		return path
+	}
+	if info, err := os.Stat(binDir); err != nil || !info.IsDir() {
+		return path
+	}
+	if len(path) == 0 {
+		return binDir
+	}
+	if first, _, _ := strings.Cut(path, string(os.PathListSeparator)); first == binDir {
+		return path
+	}
+	return binDir + string(os.PathListSeparator) + path
+}
+
+// FromToolsGo 读取 tools.go 中以空白导入声明的工具, 版本为 go.mod 中对应模块的版本, 文件不存在时返回 nil
+func FromToolsGo(projectDir string) ([]manifest.Tool, error) {
+	for _, name := range toolsGoFiles {
+		p := filepath.Join(projectDir, name)
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), p, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+		requires, err := helpers.GoModRequires(filepath.Join(projectDir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		var tools []manifest.Tool
+		for _, spec := range file.Imports {
+			pkg, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+			module := moduleOf(pkg, requires)
+			if len(module) == 0 {
+				return nil, fmt.Errorf("%s: %w: %s", name, ErrNotRequired, pkg)
+			}
+			tools = append(tools, manifest.Tool{Package: pkg, Version: requires[module], InModule: true})
+		}
+		return tools, nil
+	}
+	return nil, nil
+}
+
+// InstalledVersion 已安装工具的模块版本, 未安装时返回空字符串
+func InstalledVersion(projectDir string, t manifest.Tool) (string, error) {
+	info, err := buildinfo.ReadFile(BinPath(projectDir, t))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Path != t.Package {
+		return fmt.Sprintf("%s (%s)", info.Path, info.Main.Version), nil
+	}
+	versions := map[string]string{info.Main.Path: info.Main.Version}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		versions[dep.Path] = dep.Version
+	}
+	return versions[moduleOf(t.Package, versions)], nil
+}
+
+// CheckTool 检查已安装的工具, 未固定版本的工具只检查是否已安装
+func CheckTool(projectDir string, t manifest.Tool) (Check, error) {
+	installed, err := InstalledVersion(projectDir, t)
+	if err != nil {
+		return Check{}, err
+	}
+	check := Check{Tool: t, Installed: installed, Status: StatusOK}
+	switch {
+	case len(installed) == 0:
+		check.Status = StatusMissing
+	case !t.Pinned():
+		check.Status = StatusUnpinned
+	case installed != t.Version:
+		check.Status = StatusDrift
+	}
+	return check, nil
+}
+
+// InstallCommand 将工具安装到 ./bin/tools 的命令, tools.go 中的工具使用 go.mod 中的版本
+func InstallCommand(ctx context.Context, projectDir string, t manifest.Tool) (*exec.Cmd, error) {
+	binDir, err := filepath.Abs(filepath.Join(projectDir, filepath.FromSlash(Dir)))
+	if err != nil {
+		return nil, err
+	}
+	target := t.Package
+	if !t.InModule {
+		target += "@" + t.Version
+	}
+	cmd := exec.CommandContext(ctx, "go", "install", target)
+	cmd.Dir = projectDir
+	cmd.Env = append(os.Environ(), "GOBIN="+binDir)
+	return cmd, nil
+}
+
+// moduleOf 包所属的模块 (最长的路径前缀)
+func moduleOf(pkg string, modules map[string]string) string {
+	module := ""
+	for m := range modules {
+		if (pkg == m || strings.HasPrefix(pkg, m+"/")) && len(m) > len(module) {
+			module = m
+		}
+	}
+	return module
+}
@@ -0,0 +1,273 @@
+package upgrade
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	ActionAdd      Action = "add"
+	ActionUpdate   Action = "update"
+	ActionDelete   Action = "delete"
+	ActionMerge    Action = "merge"
+	ActionConflict Action = "conflict"
+	ActionSkip     Action = "skip"
+)
+
+// Action 对项目文件的操作
+type Action string
+
+// Change 模板更新对项目文件的影响
+type Change struct {
+	// Path 相对于项目根目录的路径
+	Path   string
+	Action Action
+	// Reason 跳过或冲突的原因
+	Reason string
+	// Conflicts 合并后的冲突数量
+	Conflicts int
+}
+
+// Labels 冲突标记中使用的名称
+type Labels struct {
+	Project string
+	Base    string
+	New     string
+}
+
+// file 文件的内容, 符号链接的内容为链接的目标
+type file struct {
+	content []byte
+	mode    fs.FileMode
+	exists  bool
+}
+
+func (f file) equal(o file) bool {
+	return f.exists == o.exists && f.mode&fs.ModeSymlink == o.mode&fs.ModeSymlink && bytes.Equal(f.content, o.content)
+}
+
+// Merge 将模板从 baseDir (创建项目时的模板) 到 newDir (新的模板) 的变更三方合并到 projectDir
+//
+// - 模板中未变化的文件: 不处理
+// - 项目中未修改的文件: 使用新的模板文件
+// - 项目和模板都修改的文件: 通过 git merge-file 合并, 无法自动合并时保留冲突标记
+// - 模板删除的文件: 项目中未修改时删除, 否则保留
+//
+// dryRun 为 true 时只返回会被修改的文件
+func Merge(baseDir, newDir, projectDir string, labels Labels, dryRun bool) ([]Change, error) {
+	paths, err := listFiles(baseDir, newDir)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, rel := range paths {
+		base, err := readFile(baseDir, rel)
+		if err != nil {
+			return nil, err
+		}
+		theirs, err := readFile(newDir, rel)
+		if err != nil {
+			return nil, err
+		}
+		if base.equal(theirs) {
+			continue
+		}
+		ours, err := readFile(projectDir, rel)
+		if err != nil {
+			return nil, err
+		}
+		change, err := mergeFile(projectDir, rel, base, ours, theirs, labels, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rel, err)
+		}
+		if len(change.Action) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func mergeFile(projectDir, rel string, base, ours, theirs file, labels Labels, dryRun bool) (Change, error) {
+	var (
+		change = Change{Path: rel}
+		target = filepath.Join(projectDir, filepath.FromSlash(rel))
+	)
+	switch {
+	case ours.equal(theirs):
+		// 项目中已经是新的内容
+		return change, nil
+	case !theirs.exists:
+		if !ours.exists {
+			return change, nil
+		}
+		if !ours.equal(base) {
+			change.Action, change.Reason = ActionSkip, "removed from the template but modified in the project"
+			return change, nil
+		}
+		change.Action = ActionDelete
+		if dryRun {
+			return change, nil
+		}
+		return change, os.Remove(target)
+	case !ours.exists:
+		if base.exists {
+			change.Action, change.Reason = ActionSkip, "removed from the project"
+			return change, nil
+		}
+		change.Action = ActionAdd
+	case ours.equal(base):
+		change.Action = ActionUpdate
+	case isSymlink(base) || isSymlink(ours) || isSymlink(theirs):
+		change.Action, change.Reason, change.Conflicts = ActionConflict, "symlink changed in both the project and the template, the project version is kept", 1
+		return change, nil
+	case isBinary(base) || isBinary(ours) || isBinary(theirs):
+		change.Action, change.Reason, change.Conflicts = ActionConflict, "binary file, the project version is kept", 1
+		return change, nil
+	default:
+		merged, conflicts, err := mergeText(base.content, ours.content, theirs.content, labels)
+		if err != nil {
+			return change, err
+		}
+		change.Action, change.Conflicts = ActionMerge, conflicts
+		if conflicts > 0 {
+			change.Action, change.Reason = ActionConflict, "conflict markers were left in the file"
+		}
+		if dryRun {
+			return change, nil
+		}
+		return change, os.WriteFile(target, merged, ours.mode.Perm())
+	}
+	if dryRun {
+		return change, nil
+	}
+	return change, writeFile(target, theirs)
+}
+
+// mergeText 通过 git merge-file 三方合并, 返回合并后的内容和冲突数量
+func mergeText(base, ours, theirs []byte, labels Labels) ([]byte, int, error) {
+	dir, err := os.MkdirTemp("", "aurora-merge-")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer os.RemoveAll(dir)
+	var files [3]string
+	for i, content := range [][]byte{ours, base, theirs} {
+		files[i] = filepath.Join(dir, fmt.Sprintf("%d", i))
+		if err = os.WriteFile(files[i], content, 0644); err != nil {
+			return nil, 0, err
+		}
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "merge-file", "-p",
+		"-L", labels.Project, "-L", labels.Base, "-L", labels.New,
+		files[0], files[1], files[2])
+	cmd.Stderr = &stderr
+	merged, err := cmd.Output()
+	if err != nil {
+		// 退出码为冲突的数量, 负数表示出错
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+			return merged, exitErr.ExitCode(), nil
+		}
+		return nil, 0, fmt.Errorf("git merge-file: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return merged, 0, nil
+}
+
+// listFiles 两个目录中所有文件的相对路径 (使用 "/" 分隔), 跳过 .git 目录
+func listFiles(dirs ...string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(p string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if info.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			seen[filepath.ToSlash(rel)] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func readFile(dir, rel string) (file, error) {
+	p := filepath.Join(dir, filepath.FromSlash(rel))
+	info, err := os.Lstat(p)
+	if os.IsNotExist(err) {
+		return file{}, nil
+	}
+	if err != nil {
+		return file{}, err
+	}
+	f := file{mode: info.Mode(), exists: true}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(p)
+		if err != nil {
+			return file{}, err
+		}
+		f.content = []byte(link)
+	case info.IsDir():
+		// 项目中同名的目录视为文件不存在, 由 writeFile 报错
+		return file{}, nil
+	default:
+		if f.content, err = os.ReadFile(p); err != nil {
+			return file{}, err
+		}
+	}
+	return f, nil
+}
+
+func writeFile(target string, f file) error {
+	if err := os.MkdirAll(filepath.Dir(target), fs.ModePerm); err != nil {
+		return err
+	}
+	if f.mode&fs.ModeSymlink != 0 {
+		_ = os.Remove(target)
+		return os.Symlink(string(f.content), target)
+	}
+	if info, err := os.Lstat(target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		_ = os.Remove(target)
+	}
+	if err := os.WriteFile(target, f.content, f.mode.Perm()); err != nil {
+		return err
+	}
+	// WriteFile 不会修改已存在文件的权限
+	return os.Chmod(target, f.mode.Perm())
+}
+
+// isBinary 包含空字符的文件视为二进制文件
+func isBinary(f file) bool {
+	content := f.content
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+func isSymlink(f file) bool {
+	return f.exists && f.mode&fs.ModeSymlink != 0
+}
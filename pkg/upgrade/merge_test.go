@@ -0,0 +1,291 @@
+package upgrade
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// entry 测试目录中的一个文件, nil 表示文件不存在
+type entry struct {
+	content string
+	link    bool
+	perm    fs.FileMode
+}
+
+func text(content string) *entry { return &entry{content: content} }
+
+func link(target string) *entry { return &entry{content: target, link: true} }
+
+var labels = Labels{Project: "project", Base: "template base", New: "template new"}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name            string
+		base, new, ours *entry
+		action          Action
+		conflicts       int
+		want            *entry
+		wantContains    []string
+	}{
+		{
+			name: "unchanged in the template",
+			base: text("a\n"), new: text("a\n"), ours: text("modified\n"),
+			want: text("modified\n"),
+		},
+		{
+			name:   "added in the template",
+			new:    text("new\n"),
+			action: ActionAdd,
+			want:   text("new\n"),
+		},
+		{
+			name: "added in the template and already in the project",
+			new:  text("same\n"), ours: text("same\n"),
+			want: text("same\n"),
+		},
+		{
+			name: "added in both with different content",
+			new:  text("template\n"), ours: text("project\n"),
+			action: ActionConflict, conflicts: 1,
+			wantContains: []string{"<<<<<<< project", "project\n", "template\n", ">>>>>>> template new"},
+		},
+		{
+			name: "updated in the template, unmodified in the project",
+			base: text("a\n"), new: text("b\n"), ours: text("a\n"),
+			action: ActionUpdate,
+			want:   text("b\n"),
+		},
+		{
+			name: "updated in the template, already updated in the project",
+			base: text("a\n"), new: text("b\n"), ours: text("b\n"),
+			want: text("b\n"),
+		},
+		{
+			name: "modified in both without overlap",
+			base: text("1\n2\n3\n4\n5\n6\n7\n"), new: text("1\n2\n3\n4\n5\n6\ntemplate\n"), ours: text("project\n2\n3\n4\n5\n6\n7\n"),
+			action: ActionMerge,
+			want:   text("project\n2\n3\n4\n5\n6\ntemplate\n"),
+		},
+		{
+			name: "modified in both on the same line",
+			base: text("a\n"), new: text("template\n"), ours: text("project\n"),
+			action: ActionConflict, conflicts: 1,
+			wantContains: []string{"<<<<<<< project", "project\n=======\ntemplate\n", ">>>>>>> template new"},
+		},
+		{
+			name: "deleted in the template, unmodified in the project",
+			base: text("a\n"), ours: text("a\n"),
+			action: ActionDelete,
+		},
+		{
+			name: "deleted in the template, modified in the project",
+			base: text("a\n"), ours: text("modified\n"),
+			action: ActionSkip,
+			want:   text("modified\n"),
+		},
+		{
+			name: "deleted in both",
+			base: text("a\n"),
+		},
+		{
+			name: "modified in the template, deleted in the project",
+			base: text("a\n"), new: text("b\n"),
+			action: ActionSkip,
+		},
+		{
+			name: "binary modified in both",
+			base: text("\x00a"), new: text("\x00b"), ours: text("\x00c"),
+			action: ActionConflict, conflicts: 1,
+			want: text("\x00c"),
+		},
+		{
+			name: "executable updated in the template",
+			base: text("#!/bin/sh\n"), new: &entry{content: "#!/bin/sh\necho\n", perm: 0755}, ours: text("#!/bin/sh\n"),
+			action: ActionUpdate,
+			want:   &entry{content: "#!/bin/sh\necho\n", perm: 0755},
+		},
+		{
+			name: "symlink updated in the template",
+			base: link("a"), new: link("b"), ours: link("a"),
+			action: ActionUpdate,
+			want:   link("b"),
+		},
+		{
+			name: "symlink changed in both",
+			base: link("a"), new: link("b"), ours: link("c"),
+			action: ActionConflict, conflicts: 1,
+			want: link("c"),
+		},
+		{
+			name: "file replaced by a symlink in the template",
+			base: text("a\n"), new: link("target"), ours: text("a\n"),
+			action: ActionUpdate,
+			want:   link("target"),
+		},
+		{
+			name: "symlink replaced by a file in the template",
+			base: link("a"), new: text("file\n"), ours: link("a"),
+			action: ActionUpdate,
+			want:   text("file\n"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir, newDir, projectDir := t.TempDir(), t.TempDir(), t.TempDir()
+			put(t, baseDir, tt.base)
+			put(t, newDir, tt.new)
+			put(t, projectDir, tt.ours)
+
+			changes, err := Merge(baseDir, newDir, projectDir, labels, false)
+			if err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			var change Change
+			if len(changes) > 1 {
+				t.Fatalf("Merge() = %+v, want at most one change", changes)
+			}
+			if len(changes) == 1 {
+				change = changes[0]
+			}
+			if change.Action != tt.action || change.Conflicts != tt.conflicts {
+				t.Fatalf("Merge() = %+v, want action %q with %d conflict(s)", change, tt.action, tt.conflicts)
+			}
+			got := get(t, projectDir)
+			switch {
+			case len(tt.wantContains) > 0:
+				if got == nil {
+					t.Fatalf("the project file is removed")
+				}
+				for _, s := range tt.wantContains {
+					if !strings.Contains(got.content, s) {
+						t.Errorf("the project file = %q, want it to contain %q", got.content, s)
+					}
+				}
+			case tt.want == nil:
+				if got != nil {
+					t.Errorf("the project file = %+v, want it removed", got)
+				}
+			default:
+				if got == nil || got.content != tt.want.content || got.link != tt.want.link {
+					t.Errorf("the project file = %+v, want %+v", got, tt.want)
+				}
+				if got != nil && !got.link && tt.want.perm != 0 && got.perm != tt.want.perm {
+					t.Errorf("the project file mode = %v, want %v", got.perm, tt.want.perm)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeDryRun(t *testing.T) {
+	baseDir, newDir, projectDir := t.TempDir(), t.TempDir(), t.TempDir()
+	for _, f := range []struct {
+		dir, path, content string
+	}{
+		{baseDir, "update.txt", "a\n"}, {newDir, "update.txt", "b\n"}, {projectDir, "update.txt", "a\n"},
+		{baseDir, "delete.txt", "a\n"}, {projectDir, "delete.txt", "a\n"},
+		{newDir, "dir/add.txt", "new\n"},
+		{baseDir, "merge.txt", "a\n"}, {newDir, "merge.txt", "b\n"}, {projectDir, "merge.txt", "c\n"},
+	} {
+		write(t, filepath.Join(f.dir, f.path), f.content)
+	}
+	changes, err := Merge(baseDir, newDir, projectDir, labels, true)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	want := map[string]Action{
+		"delete.txt":  ActionDelete,
+		"dir/add.txt": ActionAdd,
+		"merge.txt":   ActionConflict,
+		"update.txt":  ActionUpdate,
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Merge() = %+v, want %d changes", changes, len(want))
+	}
+	for _, change := range changes {
+		if want[change.Path] != change.Action {
+			t.Errorf("%s: action = %q, want %q", change.Path, change.Action, want[change.Path])
+		}
+	}
+	// dry run 不修改项目
+	for path, content := range map[string]string{"update.txt": "a\n", "delete.txt": "a\n", "merge.txt": "c\n"} {
+		if got, err := os.ReadFile(filepath.Join(projectDir, path)); err != nil || string(got) != content {
+			t.Errorf("%s = %q (err: %v), want %q", path, got, err, content)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(projectDir, "dir")); !os.IsNotExist(err) {
+		t.Errorf("dir/add.txt is created in a dry run")
+	}
+}
+
+func TestMergeSkipsGitDir(t *testing.T) {
+	baseDir, newDir, projectDir := t.TempDir(), t.TempDir(), t.TempDir()
+	write(t, filepath.Join(newDir, ".git", "HEAD"), "ref: refs/heads/main\n")
+	changes, err := Merge(baseDir, newDir, projectDir, labels, false)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Merge() = %+v, want no changes", changes)
+	}
+}
+
+const testFile = "file"
+
+func put(t *testing.T, dir string, e *entry) {
+	t.Helper()
+	if e == nil {
+		return
+	}
+	p := filepath.Join(dir, testFile)
+	if e.link {
+		if err := os.Symlink(e.content, p); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	perm := e.perm
+	if perm == 0 {
+		perm = 0644
+	}
+	if err := os.WriteFile(p, []byte(e.content), perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(t *testing.T, dir string) *entry {
+	t.Helper()
+	p := filepath.Join(dir, testFile)
+	info, err := os.Lstat(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return link(target)
+	}
+	content, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &entry{content: string(content), perm: info.Mode().Perm()}
+}
+
+func write(t *testing.T, p, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p), fs.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}